@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"voltig/config"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	addManager  string
+	addVersion  string
+	addOptional bool
+	addGroups   []string
+	addInstall  bool
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <pkg_name>...",
+	Short: "Add packages to voltig.yml",
+	Long: `Append one package entry per name to voltig.yml, keeping its comments and
+ordering. Names already listed in the file are rejected like in lint.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := config.ResolveConfigPath(configFile)
+		if err != nil {
+			// No config yet: create it where --config points
+			path = configFile
+		}
+		doc, err := config.OpenDocument(path)
+		if err != nil {
+			logger.Error("Failed to open config", "error", err)
+			os.Exit(1)
+		}
+		for _, name := range args {
+			pkg := config.Package{
+				Name:     config.PackageName{name},
+				Manager:  addManager,
+				Version:  addVersion,
+				Optional: addOptional,
+				Groups:   addGroups,
+			}
+			if err := doc.AddPackage(pkg); err != nil {
+				logger.Error("Failed to add package", "package", name, "error", err)
+				os.Exit(1)
+			}
+		}
+		if err := doc.Save(); err != nil {
+			logger.Error("Failed to write config", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Added packages", "packages", args, "path", path)
+
+		if addInstall {
+			if code := runInstall(cmd.Context(), args); code != 0 {
+				os.Exit(code)
+			}
+		}
+	},
+}
+
+func init() {
+	addCmd.Flags().StringVarP(&addManager, "manager", "m", "brew", "Package manager to install with")
+	addCmd.Flags().StringVarP(&addVersion, "version", "v", "", "Version to install (default latest)")
+	addCmd.Flags().BoolVar(&addOptional, "optional", false, "Mark the package as optional")
+	addCmd.Flags().StringSliceVarP(&addGroups, "group", "g", nil, "Profile groups to tag the package with")
+	addCmd.Flags().BoolVarP(&addInstall, "install", "i", false, "Install the packages right after adding them")
+	rootCmd.AddCommand(addCmd)
+}
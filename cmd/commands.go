@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"voltig/config"
+	"voltig/internal/platform"
+	"voltig/internal/proc"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// registerUserCommands adds the configured commands to root, creating one
+// cobra command per namespace segment so `db:migrate` becomes `voltig db migrate`.
+// Namespaces get their own help group listing their sub-commands.
+// Commands whose platforms/when conditions don't match the host, including
+// the sub-commands of such namespaces, are hidden from help.
+func registerUserCommands(root *cobra.Command, cfg *config.PackageConfig, entries []config.CommandEntry) {
+	skipped := make(map[string]string)
+	for _, e := range entries {
+		if ok, reason := platform.CommandMatches(e.Command); !ok {
+			skipped[e.Name()] = reason
+		} else if len(e.Path) > 1 {
+			if reason, found := skipped[strings.Join(e.Path[:len(e.Path)-1], ":")]; found {
+				skipped[e.Name()] = reason
+			}
+		}
+	}
+	for _, e := range entries {
+		parent := root
+		for i, segment := range e.Path {
+			child := findSubcommand(parent, segment)
+			if child == nil {
+				child = newNamespaceCommand(strings.Join(e.Path[:i+1], ":"))
+				addProjectCommand(parent, child)
+			}
+			parent = child
+		}
+		applyCustomCommand(parent, cfg, e, skipped[e.Name()])
+	}
+}
+
+// findSubcommand returns the direct child of parent with the given name.
+func findSubcommand(parent *cobra.Command, name string) *cobra.Command {
+	for _, c := range parent.Commands() {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// addProjectCommand attaches child to parent under the project help group,
+// registering a namespace-specific group on nested parents.
+func addProjectCommand(parent, child *cobra.Command) {
+	if !parent.ContainsGroup("project") {
+		parent.AddGroup(&cobra.Group{
+			ID:    "project",
+			Title: strings.ToUpper(parent.Name()[:1]) + parent.Name()[1:] + " Commands:",
+		})
+	}
+	child.GroupID = "project"
+	parent.AddCommand(child)
+}
+
+// newNamespaceCommand creates a placeholder command that only groups sub-commands.
+func newNamespaceCommand(name string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name[strings.LastIndex(name, ":")+1:],
+		Short: "✨ " + name + " commands",
+		Run: func(cmd *cobra.Command, _ []string) {
+			_ = cmd.Help()
+		},
+	}
+}
+
+// applyCustomCommand fills a (possibly placeholder) cobra command with the
+// definition of a user command. A non-empty skipReason hides the command and
+// makes invoking it an error.
+func applyCustomCommand(c *cobra.Command, cfg *config.PackageConfig, e config.CommandEntry, skipReason string) {
+	def := e.Command
+	if def.Summary != "" {
+		c.Short = "✨ " + def.Summary
+	}
+	if skipReason != "" {
+		c.Hidden = true
+		c.Run = func(_ *cobra.Command, _ []string) {
+			logger.Error("Command not available on this platform", "command", e.Name(), "reason", skipReason)
+			os.Exit(1)
+		}
+		return
+	}
+	if !def.Runnable() {
+		return
+	}
+	c.Run = func(cmd *cobra.Command, args []string) {
+		logger.Info(def.Summary)
+		ctx := cmd.Context()
+		if err := buildCustomCommand(ctx, def, cfg, args).Run(); err != nil {
+			if ctx.Err() != nil {
+				logger.Warn("Command cancelled", "command", e.Name(), "reason", ctx.Err())
+				os.Exit(exitCancelled)
+			}
+			logger.Error("Command failed", "command", e.Name(), "error", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// buildCustomCommand prepares a user command. `command:` runs through the
+// shell with args as positional parameters, `script:` runs the script with
+// the shell directly. workDir is relative to the config file, and env_file
+// values plus `environment:` entries are added to the environment. The
+// command stays attached to the terminal and is stopped when ctx is cancelled.
+func buildCustomCommand(ctx context.Context, def config.CustomCommand, cfg *config.PackageConfig, args []string) *exec.Cmd {
+	shell := def.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	allArgs := append(append([]string{}, def.Args...), args...)
+	var shellCmd *exec.Cmd
+	if def.Command != "" {
+		shellCmd = proc.Attached(ctx, shell, append([]string{"-c", def.Command, shell}, allArgs...)...)
+	} else {
+		shellCmd = proc.Attached(ctx, shell, append([]string{def.Script}, allArgs...)...)
+	}
+	baseDir := ""
+	if cfg != nil && cfg.Path != "" {
+		baseDir = filepath.Dir(cfg.Path)
+	}
+	if def.WorkDir != "" {
+		shellCmd.Dir = def.WorkDir
+		if !filepath.IsAbs(def.WorkDir) && baseDir != "" {
+			shellCmd.Dir = filepath.Join(baseDir, def.WorkDir)
+		}
+	}
+	shellCmd.Env = os.Environ()
+	if cfg != nil {
+		for _, k := range sortedKeys(cfg.Env) {
+			if _, set := os.LookupEnv(k); !set {
+				shellCmd.Env = append(shellCmd.Env, k+"="+cfg.Env[k])
+			}
+		}
+	}
+	shellCmd.Env = append(shellCmd.Env, def.Environment...)
+	shellCmd.Stdout = os.Stdout
+	shellCmd.Stderr = os.Stderr
+	shellCmd.Stdin = os.Stdin
+	return shellCmd
+}
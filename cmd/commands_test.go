@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"voltig/config"
+
+	"github.com/spf13/cobra"
+)
+
+func TestProtectedCommands_CoverCoreCommands(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if _, found := config.ProtectedCommands[c.Name()]; !found {
+			t.Errorf("core command %q is missing from config.ProtectedCommands", c.Name())
+		}
+	}
+}
+
+func TestRegisterUserCommands_Namespaces(t *testing.T) {
+	root := &cobra.Command{Use: "voltig"}
+	root.AddGroup(&cobra.Group{ID: "project", Title: "Project Commands:"})
+	entries, err := config.ValidateCommands(map[string]config.CustomCommand{
+		"build":      {Summary: "Build", Command: "go build"},
+		"db:migrate": {Summary: "Migrate", Command: "migrate up"},
+		"db": {Commands: map[string]config.CustomCommand{
+			"seed": {Summary: "Seed", Command: "seed"},
+		}},
+		"ops:cloud:deploy": {Summary: "Deploy", Command: "deploy"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registerUserCommands(root, nil, entries)
+
+	if got := len(root.Commands()); got != 3 {
+		t.Fatalf("expected 3 top-level commands, got %d", got)
+	}
+	db := findSubcommand(root, "db")
+	if db == nil || db.GroupID != "project" {
+		t.Fatalf("expected db namespace in project group, got %+v", db)
+	}
+	if !db.ContainsGroup("project") {
+		t.Errorf("expected db namespace to define its own help group")
+	}
+	for _, name := range []string{"migrate", "seed"} {
+		if c := findSubcommand(db, name); c == nil || c.Run == nil {
+			t.Errorf("expected runnable db %s sub-command", name)
+		}
+	}
+	deploy, _, err := root.Find([]string{"ops", "cloud", "deploy"})
+	if err != nil || deploy.Name() != "deploy" || deploy.Short != "✨ Deploy" {
+		t.Errorf("expected ops cloud deploy to resolve, got %v (%v)", deploy, err)
+	}
+}
+
+func TestBuildCustomCommand(t *testing.T) {
+	cfg := &config.PackageConfig{
+		Path: "/project/voltig.yml",
+		Env:  map[string]string{"VOLTIG_TEST_DOTENV": "from-dotenv"},
+	}
+	c := buildCustomCommand(context.Background(), config.CustomCommand{
+		Command:     "echo \"$1\"",
+		Args:        []string{"--env=prod"},
+		WorkDir:     "./database",
+		Environment: []string{"DB_USER=admin"},
+		Shell:       "bash",
+	}, cfg, []string{"extra"})
+	want := []string{"bash", "-c", "echo \"$1\"", "bash", "--env=prod", "extra"}
+	if strings.Join(c.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected args %v, want %v", c.Args, want)
+	}
+	if c.Dir != "/project/database" {
+		t.Errorf("expected workDir relative to config, got %s", c.Dir)
+	}
+	env := strings.Join(c.Env, "\n")
+	if !strings.Contains(env, "VOLTIG_TEST_DOTENV=from-dotenv") || !strings.HasSuffix(env, "DB_USER=admin") {
+		t.Errorf("expected dotenv and command environment, got tail %q", env[len(env)-60:])
+	}
+
+	script := buildCustomCommand(context.Background(), config.CustomCommand{Script: "./scripts/setup.sh"}, nil, nil)
+	if strings.Join(script.Args, " ") != "sh ./scripts/setup.sh" {
+		t.Errorf("unexpected script args %v", script.Args)
+	}
+}
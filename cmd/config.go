@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"voltig/config"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
 )
 
+var configExplain bool
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Show loaded configuration",
@@ -18,10 +23,98 @@ var configCmd = &cobra.Command{
 			logger.Error("Failed to load config", "error", err)
 			os.Exit(1)
 		}
+		if configExplain {
+			explainConfig(cfg)
+			return
+		}
 		logger.Info("Loaded configuration", "config", cfg)
 	},
 }
 
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a value in voltig.yml, keeping comments and formatting",
+	Long: `Set a value in voltig.yml by dotted key, keeping comments and ordering:
+
+  voltig config set commands.test.command "go test -race ./..."
+  voltig config set packages.node.version 20
+  voltig config set packages[0].optional true
+
+Missing keys are created; the result must pass the same validation as lint.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		path, err := config.ResolveConfigPath(configFile)
+		if err != nil {
+			logger.Error("Failed to find config", "error", err)
+			os.Exit(1)
+		}
+		doc, err := config.OpenDocument(path)
+		if err != nil {
+			logger.Error("Failed to open config", "error", err)
+			os.Exit(1)
+		}
+		if err := doc.Set(args[0], args[1]); err != nil {
+			logger.Error("Failed to set value", "key", args[0], "error", err)
+			os.Exit(1)
+		}
+		if err := doc.Save(); err != nil {
+			logger.Error("Failed to write config", "path", path, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Updated config", "key", args[0], "value", args[1], "path", path)
+	},
+}
+
+// explainConfig logs every loaded file and the file each entry came from.
+func explainConfig(cfg *config.PackageConfig) {
+	src := cfg.Sources
+	for i, file := range src.Files {
+		logger.Info("Config file", "order", i+1, "path", displayPath(file))
+	}
+	for _, pkg := range cfg.Packages {
+		if len(pkg.Name) == 0 {
+			continue
+		}
+		logger.Info("Package", "name", strings.Join(pkg.Name, ", "), "manager", pkg.Manager, "source", displayPath(src.Packages[pkg.Name[0]]))
+	}
+	for _, name := range sortedKeys(cfg.Commands) {
+		logger.Info("Command", "name", name, "source", displayPath(src.Commands[name]))
+	}
+	for _, name := range sortedKeys(cfg.Profiles) {
+		logger.Info("Profile", "name", name, "source", displayPath(src.Profiles[name]))
+	}
+	if cfg.DefaultProfile != "" {
+		logger.Info("Default profile", "name", cfg.DefaultProfile, "source", displayPath(src.DefaultProfile))
+	}
+}
+
+// displayPath shortens path relative to the working directory when possible.
+func displayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func init() {
+	configCmd.Flags().BoolVar(&configExplain, "explain", false, "Show which file each package, command and profile came from")
+	configCmd.AddCommand(configSetCmd)
 	rootCmd.AddCommand(configCmd)
 }
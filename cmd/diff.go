@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/semver"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// exitDrift is the exit code of `voltig diff` when the machine differs from the config.
+const exitDrift = 2
+
+var (
+	diffOutput       string
+	diffDeclaredOnly bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how the machine differs from voltig.yml",
+	Long: `Compare voltig.yml with what is installed: declared packages that are
+missing (-), installed at a version that doesn't satisfy their constraint (~),
+and installed packages the config doesn't declare (+), as reported by
+` + "`voltig scan`" + `. Packages installed only as dependencies of others
+(brew, apt and pacman track this) are not reported as undeclared.
+--declared-only leaves out undeclared packages.
+
+Exits 0 when the machine matches the config, 2 when it drifted and 1 on
+errors, so it can gate CI jobs.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if diffOutput != "text" && diffOutput != "json" {
+			logger.Error("Invalid --output, expected text or json", "output", diffOutput)
+			os.Exit(1)
+		}
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		m := manager.ForOS()
+		if m == nil {
+			logger.Error("No supported package manager found for this OS")
+			os.Exit(1)
+		}
+		pkgs, err := profilePackages(cfg, nil)
+		if err != nil {
+			logger.Error("Failed to resolve profile", "error", err)
+			os.Exit(1)
+		}
+		var scanManager string
+		var scanned []models.PackageStatus
+		if !diffDeclaredOnly {
+			if scanManager, scanned, err = scanSystemPackages(cmd.Context()); err != nil {
+				logger.Error("Failed to scan installed packages", "error", err)
+				os.Exit(1)
+			}
+			deps, err := scanDependencies(cmd.Context(), scanManager)
+			if err != nil {
+				logger.Error("Failed to list packages installed as dependencies", "error", err, "hint", "use --declared-only to skip undeclared packages")
+				os.Exit(1)
+			}
+			scanned = withoutDependencies(scanned, deps)
+		}
+		drift, unknown := computeDrift(cmd.Context(), cfg, selectPackages(pkgs, nil).Targets, m, scanManager, scanned)
+		if err := writeDrift(os.Stdout, cfg, drift, diffOutput); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
+		}
+		switch {
+		case len(unknown) > 0:
+			logger.Error("Failed to check packages", "packages", unknown)
+			os.Exit(1)
+		case len(drift) > 0:
+			os.Exit(exitDrift)
+		}
+	},
+}
+
+// Drift kinds.
+const (
+	driftMissing    = "missing"
+	driftMismatch   = "mismatch"
+	driftUndeclared = "undeclared"
+)
+
+// driftItem is one difference between the config and the machine.
+type driftItem struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Manager   string `json:"manager"`
+	Declared  string `json:"declared,omitempty"`  // version constraint in voltig.yml
+	Installed string `json:"installed,omitempty"` // installed version
+}
+
+/*
+computeDrift compares pkgs with the machine using GetStatus: each name that is
+missing, or installed at a version violating the package's constraint, is
+reported. Scanned packages not declared anywhere in cfg, in any profile, are
+reported as undeclared; callers leave dependencies out of scanned. Names
+whose status can't be read are returned separately.
+*/
+func computeDrift(ctx context.Context, cfg *config.PackageConfig, pkgs []config.Package, m manager.PackageManager, scanManager string, scanned []models.PackageStatus) (drift []driftItem, unknown []string) {
+	drift = []driftItem{}
+	for _, pkg := range pkgs {
+		statuses, err := m.GetStatus(ctx, models.ToModel(pkg))
+		if err != nil {
+			logger.Warn("Failed to get package status", "package", strings.Join(pkg.Name, ", "), "error", err)
+			unknown = append(unknown, pkg.Name...)
+			continue
+		}
+		for _, status := range statuses {
+			item := driftItem{Name: status.Name, Manager: pkg.Manager, Declared: pkg.Version, Installed: installedVersion(status)}
+			switch check := constraintCheck(pkg, status); {
+			case !strings.HasPrefix(status.Status, "installed"):
+				item.Kind = driftMissing
+			case check == semver.StatusOutdated || check == semver.StatusUnsatisfied:
+				item.Kind = driftMismatch
+			default:
+				continue
+			}
+			drift = append(drift, item)
+		}
+	}
+	declared := make(map[string]bool)
+	for _, pkg := range cfg.Packages {
+		for _, name := range pkg.Name {
+			declared[name] = true
+		}
+	}
+	for _, p := range scanned {
+		// Versioned formulae such as node@20 provide the declared node
+		base, _, _ := strings.Cut(p.Name, "@")
+		if !declared[p.Name] && !declared[base] {
+			drift = append(drift, driftItem{Kind: driftUndeclared, Name: p.Name, Manager: scanManager, Installed: p.Version.Raw})
+		}
+	}
+	return drift, unknown
+}
+
+// writeDrift writes drift as a unified diff from the config to the machine,
+// or as JSON.
+func writeDrift(w io.Writer, cfg *config.PackageConfig, drift []driftItem, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(drift)
+	}
+	if len(drift) == 0 {
+		logger.Info("The machine matches the config")
+		return nil
+	}
+	lines := []string{
+		ErrorStyle.Render("--- " + displayPath(cfg.Path)),
+		SuccessStyle.Render("+++ installed"),
+	}
+	var missing, mismatched, undeclared int
+	for _, d := range drift {
+		declared := d.Declared
+		if declared == "" {
+			declared = "latest"
+		}
+		switch d.Kind {
+		case driftMissing:
+			missing++
+			lines = append(lines, ErrorStyle.Render(fmt.Sprintf("- %s %s (%s)", d.Name, declared, d.Manager)))
+		case driftMismatch:
+			mismatched++
+			lines = append(lines, WarningStyle.Render(fmt.Sprintf("~ %s %s, installed %s (%s)", d.Name, declared, d.Installed, d.Manager)))
+		case driftUndeclared:
+			undeclared++
+			lines = append(lines, SuccessStyle.Render(fmt.Sprintf("+ %s %s (%s)", d.Name, d.Installed, d.Manager)))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%d missing, %d mismatched, %d undeclared", missing, mismatched, undeclared))
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "text", "Output format: text or json")
+	diffCmd.Flags().BoolVar(&diffDeclaredOnly, "declared-only", false, "Only check declared packages, skipping the scan for undeclared ones")
+	addProfileFlag(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/models"
+)
+
+func TestComputeDrift(t *testing.T) {
+	setTestLogger(t)
+	cfg := &config.PackageConfig{Path: "voltig.yml", Packages: []config.Package{
+		{Name: []string{"jq"}, Manager: "brew"},
+		{Name: []string{"node"}, Manager: "brew", Version: "^20"},
+		{Name: []string{"go"}, Manager: "brew", Version: "^1.22"},
+		{Name: []string{"rg", "fd"}, Manager: "brew"},
+	}}
+	m := &fakeManager{installed: map[string]string{"node": "18.19.0", "go": "1.22.1", "rg": "14.0"}}
+	scanned := []models.PackageStatus{{Name: "go", Version: models.ParseVersion("1.22.1", "brew")}, {Name: "wget", Version: models.ParseVersion("1.21.4", "brew")}}
+
+	drift, unknown := computeDrift(context.Background(), cfg, cfg.Packages, m, "brew", scanned)
+	if len(unknown) > 0 {
+		t.Fatalf("unexpected status errors for %v", unknown)
+	}
+	var got []string
+	for _, d := range drift {
+		got = append(got, d.Kind+" "+d.Name)
+	}
+	want := "missing jq, mismatch node, missing fd, undeclared wget"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("drift = %q, want %q", strings.Join(got, ", "), want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDrift(&buf, cfg, drift, "text"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"--- voltig.yml", "- jq latest (brew)", "~ node ^20, installed 18.19.0 (brew)", "+ wget 1.21.4 (brew)", "2 missing, 1 mismatched, 1 undeclared"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in report:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := writeDrift(&buf, cfg, drift, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []driftItem
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 4 || decoded[1].Installed != "18.19.0" {
+		t.Errorf("unexpected JSON %s (%v)", buf.String(), err)
+	}
+}
+
+func TestComputeDrift_Dependencies(t *testing.T) {
+	setTestLogger(t)
+	cfg := &config.PackageConfig{Path: "voltig.yml", Packages: []config.Package{{Name: []string{"jq"}, Manager: "brew"}}}
+	m := &fakeManager{installed: map[string]string{"jq": "1.7.1"}}
+	scanned := []models.PackageStatus{
+		{Name: "jq", Version: models.ParseVersion("1.7.1", "brew")},
+		{Name: "oniguruma", Version: models.ParseVersion("6.9.9", "brew")},
+		{Name: "jq@1.6", Version: models.ParseVersion("1.6", "brew")},
+	}
+	deps := map[string]bool{"oniguruma": true}
+
+	drift, _ := computeDrift(context.Background(), cfg, cfg.Packages, m, "brew", withoutDependencies(scanned, deps))
+	if len(drift) != 0 {
+		t.Errorf("dependency or versioned formula reported as drift: %+v", drift)
+	}
+}
@@ -0,0 +1,394 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/proc"
+	"voltig/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment voltig runs in",
+	Long: `Diagnose common reasons voltig fails on a machine: which package managers are
+available and their versions, binaries shadowed by others earlier in PATH
+(e.g. brew node vs nvm node), the config file in use, write access to the
+Homebrew prefix and the voltig state directory, and package manager locks
+held by another process. Every problem comes with a suggested fix.
+
+Exits 1 when a check fails; warnings don't change the exit code.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		checks := runDoctor(cmd.Context(), hostDoctorEnv(), configFile)
+		if failed := writeDoctor(os.Stdout, checks); failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// Outcomes of a doctor check.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the outcome of one check, with a fix when it didn't pass.
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+}
+
+// doctorEnv is the machine the checks inspect, so tests can fake it.
+type doctorEnv struct {
+	goos string
+	path string // PATH
+	// run returns the trimmed stdout of a command
+	run func(ctx context.Context, name string, arg ...string) (string, error)
+	// lockHeld reports whether another process holds the lock file at path,
+	// taken with flock or else fcntl
+	lockHeld func(path string, flock bool) (bool, error)
+	// aptLocks are the lock files apt and dpkg take
+	aptLocks []string
+}
+
+func hostDoctorEnv() doctorEnv {
+	return doctorEnv{
+		goos: runtime.GOOS,
+		path: os.Getenv("PATH"),
+		run: func(ctx context.Context, name string, arg ...string) (string, error) {
+			out, err := proc.Command(ctx, name, arg...).Output()
+			return strings.TrimSpace(string(out)), err
+		},
+		lockHeld: lockHeld,
+		aptLocks: []string{"/var/lib/dpkg/lock-frontend", "/var/lib/dpkg/lock", "/var/lib/apt/lists/lock", "/var/cache/apt/archives/lock"},
+	}
+}
+
+// doctorManagers are the package managers doctor looks for, by OS.
+var doctorManagers = []struct {
+	name string
+	goos []string
+}{
+	{"brew", []string{"darwin", "linux"}},
+	{"apt-get", []string{"linux"}},
+	{"dnf", []string{"linux"}},
+	{"pacman", []string{"linux"}},
+	{"choco", []string{"windows"}},
+	{"winget", []string{"windows"}},
+}
+
+// doctorBinaries are checked for shadowing in addition to the config packages.
+var doctorBinaries = []string{"node", "python", "python3", "ruby"}
+
+// runDoctor runs every check against env using the config at configPath.
+func runDoctor(ctx context.Context, env doctorEnv, configPath string) []doctorCheck {
+	checks, brewPrefix := checkManagers(ctx, env)
+	cfgCheck, cfg := checkConfig(configPath)
+	checks = append(checks, cfgCheck)
+	names := append([]string{}, doctorBinaries...)
+	if cfg != nil {
+		for _, pkg := range cfg.Packages {
+			names = append(names, pkg.Name...)
+		}
+	}
+	checks = append(checks, checkShadowed(env, names, brewPrefix)...)
+	checks = append(checks, checkWritable(brewPrefix)...)
+	checks = append(checks, checkLocks(env, brewPrefix)...)
+	return checks
+}
+
+/*
+checkManagers reports the package managers found in PATH with their versions,
+and whether voltig has one it can install packages with. It returns the
+Homebrew prefix, or "" without Homebrew.
+*/
+func checkManagers(ctx context.Context, env doctorEnv) ([]doctorCheck, string) {
+	var checks []doctorCheck
+	var brewPrefix string
+	for _, m := range doctorManagers {
+		if !contains(m.goos, env.goos) {
+			continue
+		}
+		found := findInPath(env.path, m.name, env.goos)
+		if len(found) == 0 {
+			continue
+		}
+		version, err := env.run(ctx, found[0], "--version")
+		if err != nil {
+			checks = append(checks, doctorCheck{Name: m.name, Status: checkWarn, Detail: fmt.Sprintf("%s --version failed: %v", found[0], err),
+				Fix: "Reinstall " + m.name + " or remove the broken binary from PATH"})
+			continue
+		}
+		version, _, _ = strings.Cut(version, "\n")
+		checks = append(checks, doctorCheck{Name: m.name, Status: checkOK, Detail: fmt.Sprintf("%s (%s)", version, found[0])})
+		if m.name == "brew" {
+			if prefix, err := env.run(ctx, found[0], "--prefix"); err == nil {
+				brewPrefix = prefix
+			}
+		}
+	}
+	// voltig installs through Homebrew on macOS, see manager.ForOS
+	switch {
+	case env.goos == "darwin" && brewPrefix != "":
+		checks = append(checks, doctorCheck{Name: "package manager", Status: checkOK, Detail: "voltig uses brew"})
+	case env.goos == "darwin":
+		checks = append(checks, doctorCheck{Name: "package manager", Status: checkFail, Detail: "Homebrew not found in PATH",
+			Fix: "Install Homebrew from https://brew.sh and open a new shell"})
+	default:
+		checks = append(checks, doctorCheck{Name: "package manager", Status: checkWarn, Detail: "voltig can't install packages on " + env.goos + " yet",
+			Fix: "Use voltig scan and custom commands here, or run voltig on macOS with Homebrew"})
+	}
+	return checks, brewPrefix
+}
+
+// checkConfig reports the config voltig would load for configPath and returns
+// it if it loads.
+func checkConfig(configPath string) (doctorCheck, *config.PackageConfig) {
+	check := doctorCheck{Name: "config"}
+	resolved, err := config.ResolveConfigPath(configPath)
+	if err != nil {
+		check.Status, check.Detail = checkWarn, "no "+configPath+" in this directory or its parents"
+		check.Fix = "Run voltig init, or pass --config with the path of your voltig.yml"
+		return check, nil
+	}
+	cfg, err := config.LoadConfig(resolved)
+	if err != nil {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("%s is invalid: %v", displayPath(resolved), err)
+		check.Fix = "Run voltig lint to list the problems"
+		return check, nil
+	}
+	check.Status, check.Detail = checkOK, strings.Join(mapSlice(cfg.Sources.Files, displayPath), ", ")
+	return check, cfg
+}
+
+/*
+checkShadowed warns about each name that resolves to several binaries in PATH,
+naming who installed each one; only the first is ever run. Copies from
+different version managers, e.g. nvm and volta, are reported as a conflict.
+*/
+func checkShadowed(env doctorEnv, names []string, brewPrefix string) []doctorCheck {
+	sort.Strings(names)
+	var checks []doctorCheck
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		found := findInPath(env.path, name, env.goos)
+		if len(found) < 2 {
+			continue
+		}
+		var managers []string
+		described := mapSlice(found, func(path string) string {
+			if det, ok := manager.DetectVersionManager(path); ok {
+				if !contains(managers, det.Manager) {
+					managers = append(managers, det.Manager)
+				}
+				return path + " (" + strings.TrimSpace(det.Manager+" "+det.Version) + ")"
+			}
+			if brewPrefix != "" && strings.HasPrefix(path, brewPrefix+string(filepath.Separator)) {
+				return path + " (brew)"
+			}
+			return path
+		})
+		check := doctorCheck{Name: name, Status: checkWarn,
+			Detail: described[0] + " shadows " + strings.Join(described[1:], ", "),
+			Fix:    "Remove the copies you don't use, or reorder PATH so the one you want comes first"}
+		if len(managers) > 1 {
+			check.Detail = name + " is managed by both " + strings.Join(managers, " and ") + ": " + check.Detail
+			check.Fix = "Manage " + name + " with one version manager and uninstall it from the others (" + strings.Join(managers[1:], ", ") + ")"
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "PATH", Status: checkOK, Detail: "no shadowed binaries"})
+	}
+	return checks
+}
+
+// checkWritable checks that Homebrew and voltig can write where they install
+// and record state.
+func checkWritable(brewPrefix string) []doctorCheck {
+	var checks []doctorCheck
+	if brewPrefix != "" {
+		check := doctorCheck{Name: "brew prefix", Status: checkOK, Detail: brewPrefix + " is writable"}
+		for _, dir := range []string{brewPrefix, filepath.Join(brewPrefix, "bin"), filepath.Join(brewPrefix, "Cellar")} {
+			if _, err := os.Stat(dir); err == nil && !writable(dir) {
+				check.Status, check.Detail = checkFail, dir+" is not writable by "+currentUsername()
+				check.Fix = "sudo chown -R $(whoami) " + brewPrefix
+				break
+			}
+		}
+		checks = append(checks, check)
+	}
+	if path := state.DefaultPath(); path != "" {
+		dir := existingParent(filepath.Dir(path))
+		check := doctorCheck{Name: "state directory", Status: checkOK, Detail: displayPath(filepath.Dir(path))}
+		if !writable(dir) {
+			check.Status, check.Detail = checkFail, dir+" is not writable by "+currentUsername()
+			check.Fix = "Make it writable, or set XDG_STATE_HOME to a writable directory"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkLocks warns about Homebrew and apt locks another process holds, which
+// make installs wait or fail.
+func checkLocks(env doctorEnv, brewPrefix string) []doctorCheck {
+	type lockFile struct {
+		owner, path, fix string
+		flock            bool // Homebrew uses flock, apt and dpkg fcntl
+	}
+	var locks []lockFile
+	if brewPrefix != "" {
+		paths, _ := filepath.Glob(filepath.Join(brewPrefix, "var", "homebrew", "locks", "*.lock"))
+		for _, path := range paths {
+			locks = append(locks, lockFile{"brew", path, "Wait for the other brew process to finish (pgrep -fl brew)", true})
+		}
+	}
+	if len(findInPath(env.path, "apt-get", env.goos)) > 0 {
+		for _, path := range env.aptLocks {
+			locks = append(locks, lockFile{"apt", path, "Wait for the other apt or dpkg process, often unattended-upgrades, to finish (pgrep -fl 'apt|dpkg')", false})
+		}
+	}
+	var checks []doctorCheck
+	held := make(map[string]bool)
+	for _, l := range locks {
+		// Locks that can't be opened, e.g. root-only apt locks, are skipped
+		if ok, err := env.lockHeld(l.path, l.flock); err != nil || !ok || held[l.owner] {
+			continue
+		}
+		held[l.owner] = true
+		checks = append(checks, doctorCheck{Name: l.owner + " lock", Status: checkWarn, Detail: l.path + " is held by another process", Fix: l.fix})
+	}
+	if len(checks) == 0 && len(locks) > 0 {
+		checks = append(checks, doctorCheck{Name: "locks", Status: checkOK, Detail: "no package manager locks held"})
+	}
+	return checks
+}
+
+// writeDoctor prints checks with their fixes and a summary, and reports
+// whether any check failed.
+func writeDoctor(w io.Writer, checks []doctorCheck) bool {
+	counts := make(map[string]int)
+	for _, c := range checks {
+		counts[c.Status]++
+		var mark string
+		switch c.Status {
+		case checkOK:
+			mark = SuccessStyle.Render("✓")
+		case checkWarn:
+			mark = WarningStyle.Render("!")
+		case checkFail:
+			mark = ErrorStyle.Render("✗")
+		}
+		_, _ = fmt.Fprintf(w, "%s %s: %s\n", mark, c.Name, c.Detail)
+		if c.Fix != "" {
+			_, _ = fmt.Fprintf(w, "    fix: %s\n", c.Fix)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d ok, %d warnings, %d failed\n", counts[checkOK], counts[checkWarn], counts[checkFail])
+	return counts[checkFail] > 0
+}
+
+/*
+findInPath returns every executable named name in the directories of the
+PATH list, in lookup order. Entries reached through several PATH directories
+or symlinks to the same file are listed once.
+*/
+func findInPath(pathList, name, goos string) []string {
+	candidates := []string{name}
+	if goos == "windows" && filepath.Ext(name) == "" {
+		candidates = []string{name + ".exe", name + ".cmd", name + ".bat"}
+	}
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		for _, c := range candidates {
+			path := filepath.Join(dir, c)
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() || (goos != "windows" && info.Mode()&0o111 == 0) {
+				continue
+			}
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				real = path
+			}
+			if !seen[real] {
+				seen[real] = true
+				found = append(found, path)
+			}
+		}
+	}
+	return found
+}
+
+// writable reports whether the current user can create files in dir.
+func writable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".voltig-doctor-*")
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return true
+}
+
+// existingParent returns dir or its closest ancestor that exists.
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "the current user"
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func mapSlice(values []string, fn func(string) string) []string {
+	mapped := make([]string, len(values))
+	for i, v := range values {
+		mapped[i] = fn(v)
+	}
+	return mapped
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
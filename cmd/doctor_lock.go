@@ -0,0 +1,38 @@
+//go:build darwin || linux
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+/*
+lockHeld reports whether another process holds the lock file at path, with
+flock (Homebrew) when flock is set and fcntl (apt, dpkg) otherwise; on Linux
+neither probe sees the other kind of lock. The flock probe takes the lock for
+an instant when it is free.
+*/
+func lockHeld(path string, flock bool) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+	fd := f.Fd()
+	if !flock {
+		lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: 0}
+		if err := syscall.FcntlFlock(fd, syscall.F_GETLK, &lk); err != nil {
+			return false, err
+		}
+		return lk.Type != syscall.F_UNLCK, nil
+	}
+	if err := syscall.Flock(int(fd), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, syscall.Flock(int(fd), syscall.LOCK_UN)
+}
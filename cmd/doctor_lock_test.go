@@ -0,0 +1,37 @@
+//go:build darwin || linux
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestLockHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "update.lock")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := lockHeld(path, true); err != nil || held {
+		t.Fatalf("free lock: held = %v, err = %v", held, err)
+	}
+	// flock locks conflict between open files, even within one process
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := lockHeld(path, true); err != nil || !held {
+		t.Errorf("locked: held = %v, err = %v", held, err)
+	}
+	// Linux keeps fcntl and flock locks apart
+	if held, err := lockHeld(path, false); runtime.GOOS == "linux" && (err != nil || held) {
+		t.Errorf("fcntl probe: held = %v, err = %v", held, err)
+	}
+}
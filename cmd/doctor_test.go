@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeExecutable creates an executable file name in dir.
+func writeExecutable(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckShadowed(t *testing.T) {
+	root := t.TempDir()
+	nvm := filepath.Join(root, ".nvm", "versions", "node", "v20.11.0", "bin")
+	brew := filepath.Join(root, "homebrew")
+	brewBin := filepath.Join(brew, "bin")
+	for _, dir := range []string{nvm, brewBin} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nvmNode := writeExecutable(t, nvm, "node")
+	brewNode := writeExecutable(t, brewBin, "node")
+	writeExecutable(t, brewBin, "jq")
+	// A symlink to the same binary isn't a second copy
+	linkDir := filepath.Join(root, "links")
+	if err := os.Mkdir(linkDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(brewBin, "jq"), filepath.Join(linkDir, "jq")); err != nil {
+		t.Fatal(err)
+	}
+
+	env := doctorEnv{goos: "linux", path: strings.Join([]string{nvm, brewBin, linkDir}, string(os.PathListSeparator))}
+	checks := checkShadowed(env, []string{"node", "jq", "node"}, brew)
+	if len(checks) != 1 {
+		t.Fatalf("checks = %+v, want one warning for node", checks)
+	}
+	want := nvmNode + " (nvm 20.11.0) shadows " + brewNode + " (brew)"
+	if checks[0].Name != "node" || checks[0].Status != checkWarn || checks[0].Detail != want {
+		t.Errorf("check = %+v, want detail %q", checks[0], want)
+	}
+
+	// node from both nvm and volta is a conflict
+	volta := filepath.Join(root, ".volta", "bin")
+	if err := os.MkdirAll(volta, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeExecutable(t, volta, "node")
+	env.path = strings.Join([]string{volta, nvm}, string(os.PathListSeparator))
+	checks = checkShadowed(env, []string{"node"}, brew)
+	if len(checks) != 1 || !strings.HasPrefix(checks[0].Detail, "node is managed by both volta and nvm") || !strings.Contains(checks[0].Fix, "(nvm)") {
+		t.Errorf("expected a volta/nvm conflict, got %+v", checks)
+	}
+
+	env.path = brewBin
+	if checks := checkShadowed(env, []string{"node"}, brew); len(checks) != 1 || checks[0].Status != checkOK {
+		t.Errorf("expected no shadowing, got %+v", checks)
+	}
+}
+
+func TestCheckLocks(t *testing.T) {
+	prefix := t.TempDir()
+	locks := filepath.Join(prefix, "var", "homebrew", "locks")
+	if err := os.MkdirAll(locks, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	held := filepath.Join(locks, "update.lock")
+	for _, name := range []string{"update.lock", "node.formula.lock"} {
+		if err := os.WriteFile(filepath.Join(locks, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := doctorEnv{goos: "darwin", lockHeld: func(path string, flock bool) (bool, error) { return flock && path == held, nil }}
+	checks := checkLocks(env, prefix)
+	if len(checks) != 1 || checks[0].Name != "brew lock" || !strings.Contains(checks[0].Detail, held) || checks[0].Fix == "" {
+		t.Errorf("checks = %+v, want a warning for %s", checks, held)
+	}
+}
+
+func TestWriteDoctor(t *testing.T) {
+	var buf bytes.Buffer
+	failed := writeDoctor(&buf, []doctorCheck{
+		{Name: "brew", Status: checkOK, Detail: "Homebrew 4.2.0"},
+		{Name: "brew prefix", Status: checkFail, Detail: "/opt/homebrew is not writable", Fix: "sudo chown -R $(whoami) /opt/homebrew"},
+	})
+	if !failed {
+		t.Error("expected a failed check to be reported")
+	}
+	for _, want := range []string{"brew: Homebrew 4.2.0", "fix: sudo chown", "1 ok, 0 warnings, 1 failed"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"voltig/internal/journal"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/semver"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySince  string
+	historyOutput string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the package operations voltig ran on this machine",
+	Long: `List every install, update and remove voltig ran on this machine, with the
+package versions before and after, the outcome and the user who ran it. The
+journal lives next to the ownership state, in
+$XDG_STATE_HOME/voltig/journal.jsonl (default ~/.local/state).
+
+--since accepts a duration (24h) or a date (2026-01-31).`,
+	Args: cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		if historyOutput != "text" && historyOutput != "json" {
+			logger.Error("Invalid --output, expected text or json", "output", historyOutput)
+			os.Exit(1)
+		}
+		var since time.Time
+		if historySince != "" {
+			var err error
+			if since, err = parseSince(historySince, time.Now()); err != nil {
+				logger.Error("Invalid --since", "error", err)
+				os.Exit(1)
+			}
+		}
+		entries, err := journal.Open(journal.DefaultPath()).Entries()
+		if err != nil {
+			logger.Error("Failed to read journal", "error", err)
+			os.Exit(1)
+		}
+		if err := writeHistory(os.Stdout, filterSince(entries, since), historyOutput); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// parseSince parses --since as a duration before now or as a date.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected a duration (24h) or a date (2006-01-02), got %q", s)
+}
+
+// filterSince returns the entries recorded at or after since.
+func filterSince(entries []journal.Entry, since time.Time) []journal.Entry {
+	filtered := []journal.Entry{}
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// writeHistory writes entries as an aligned table or as JSON.
+func writeHistory(w io.Writer, entries []journal.Entry, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		logger.Info("No operations recorded")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tTIME\tUSER\tOP\tPACKAGE\tBEFORE\tAFTER\tSTATUS")
+	for _, e := range entries {
+		status := e.Status
+		if e.Undoes != 0 {
+			status += fmt.Sprintf(" (undo #%d)", e.Undoes)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04"), e.User, e.Op,
+			e.Package, orDash(e.Before), orDash(e.After), status)
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// installedVersion returns the version in status, or "" if not installed.
+func installedVersion(status models.PackageStatus) string {
+	if !strings.HasPrefix(status.Status, "installed") {
+		return ""
+	}
+	if v, err := semver.Parse(status.Version.Raw); err == nil {
+		return v.Original
+	}
+	return status.Version.Raw
+}
+
+// installedVersions returns the installed version of each name of pkgs.
+func installedVersions(ctx context.Context, m manager.PackageManager, pkgs []models.Package) map[string]string {
+	versions := make(map[string]string)
+	for _, pkg := range pkgs {
+		statuses, _ := m.GetStatus(ctx, pkg)
+		for _, status := range statuses {
+			versions[status.Name] = installedVersion(status)
+		}
+	}
+	return versions
+}
+
+/*
+journalOp snapshots the installed versions of pkgs before op runs on them and
+returns a function that records the outcome, given the results of
+manager.PkgOperation, in the journal. undoes is the ID of the entry op
+reverses, or 0.
+*/
+func journalOp(ctx context.Context, m manager.PackageManager, op string, pkgs []models.Package, undoes int) func(successes, failures, cancelled []string) {
+	before := installedVersions(ctx, m, pkgs)
+	return func(successes, failures, cancelled []string) {
+		outcome := make(map[string]string)
+		for _, name := range successes {
+			outcome[name] = journal.StatusOK
+		}
+		for _, name := range failures {
+			outcome[name] = journal.StatusFailed
+		}
+		for _, name := range cancelled {
+			outcome[name] = journal.StatusCancelled
+		}
+		// Versions are read even if the run was interrupted
+		after := installedVersions(context.WithoutCancel(ctx), m, pkgs)
+		var entries []journal.Entry
+		for _, pkg := range pkgs {
+			for _, name := range pkg.Name {
+				if outcome[name] == "" {
+					continue
+				}
+				entries = append(entries, journal.Entry{Op: op, Package: name, Manager: pkg.Manager, Before: before[name], After: after[name], Status: outcome[name], Undoes: undoes})
+			}
+		}
+		j := journal.Open(journal.DefaultPath())
+		if err := j.Record(entries...); err != nil {
+			logger.Warn("Failed to write journal", "path", j.Path, "error", err)
+		}
+	}
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show operations since a duration ago (24h) or a date (2026-01-31)")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format: text or json")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"voltig/internal/journal"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	if got, err := parseSince("24h", now); err != nil || !got.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("parseSince(24h) = %v, %v", got, err)
+	}
+	if got, err := parseSince("2026-03-01", now); err != nil || !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("parseSince(date) = %v, %v", got, err)
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	setTestLogger(t)
+	now := time.Now()
+	entries := []journal.Entry{
+		{ID: 1, Time: now.Add(-48 * time.Hour), User: "dev", Op: "install", Package: "jq", Manager: "brew", After: "1.7.1", Status: journal.StatusOK},
+		{ID: 2, Time: now, User: "dev", Op: "remove", Package: "jq", Manager: "brew", Before: "1.7.1", Status: journal.StatusOK, Undoes: 1},
+	}
+	recent := filterSince(entries, now.Add(-time.Hour))
+	if len(recent) != 1 || recent[0].ID != 2 {
+		t.Fatalf("filterSince = %+v", recent)
+	}
+	var buf bytes.Buffer
+	if err := writeHistory(&buf, recent, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "remove  jq       1.7.1   -      ok (undo #1)") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
+
+func TestJournalOpAndUndo(t *testing.T) {
+	setTestLogger(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	ctx := context.Background()
+	m := &fakeManager{installed: map[string]string{"node": "18.19.0"}}
+	j := journal.Open(journal.DefaultPath())
+
+	pkgs := []models.Package{{Name: []string{"jq"}, Manager: "brew"}}
+	done := journalOp(ctx, m, manager.OpInstall, pkgs, 0)
+	ok, failed, cancelled := manager.PkgOperation(ctx, "Installing", "installed", pkgs, m.Install)
+	done(ok, failed, cancelled)
+	entries, _ := j.Entries()
+	if len(entries) != 1 || entries[0].Before != "" || entries[0].After != "9.9.9" || entries[0].Status != journal.StatusOK {
+		t.Fatalf("unexpected journal: %+v", entries)
+	}
+
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := runUndo(ctx, m, j, nil, p, &out); code != 0 {
+		t.Fatalf("undo failed with code %d", code)
+	}
+	if _, installed := m.installed["jq"]; installed {
+		t.Error("undoing an install should remove the package")
+	}
+	entries, _ = j.Entries()
+	if len(entries) != 2 || entries[1].Op != manager.OpRemove || entries[1].Undoes != 1 {
+		t.Fatalf("undo not journaled: %+v", entries)
+	}
+
+	// The undo itself is skipped, leaving nothing else to undo
+	if code := runUndo(ctx, m, j, nil, p, &out); code == 0 {
+		t.Error("expected nothing left to undo")
+	}
+	if code := runUndo(ctx, m, j, []string{"1"}, p, &out); code == 0 {
+		t.Error("expected an already undone operation to be refused")
+	}
+}
+
+func TestUndoPlan(t *testing.T) {
+	ctx := context.Background()
+	m := &fakeManager{installed: map[string]string{}}
+	tests := []struct {
+		name    string
+		entry   journal.Entry
+		op      string
+		version string
+	}{
+		{"install of missing package", journal.Entry{Op: "install", Package: "jq", After: "1.7", Status: journal.StatusOK}, manager.OpRemove, ""},
+		{"remove", journal.Entry{Op: "remove", Package: "jq", Before: "1.7", Status: journal.StatusOK}, manager.OpInstall, "1.7"},
+		{"update", journal.Entry{Op: "update", Package: "jq", Before: "1.6", After: "1.7", Status: journal.StatusOK}, manager.OpInstall, "1.6"},
+		{"failed", journal.Entry{Op: "install", Package: "jq", Status: journal.StatusFailed}, "", ""},
+		{"no change", journal.Entry{Op: "update", Package: "jq", Before: "1.7", After: "1.7", Status: journal.StatusOK}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, pkg, err := undoPlan(ctx, m, tt.entry)
+			if tt.op == "" {
+				if err == nil {
+					t.Errorf("expected an error, got %s", op)
+				}
+				return
+			}
+			if err != nil || op != tt.op || pkg.Version != tt.version {
+				t.Errorf("undoPlan = %s %q, %v; want %s %q", op, pkg.Version, err, tt.op, tt.version)
+			}
+		})
+	}
+
+	lister := &listingFake{fakeManager: m, versions: map[string][]string{"node": {"22.3.0", "20"}}}
+	removed := journal.Entry{Op: "remove", Package: "node", Before: "20.11.1_1", Status: journal.StatusOK}
+	if _, pkg, err := undoPlan(ctx, lister, removed); err != nil || pkg.Version != "20" {
+		t.Errorf("got %q, %v; want the node@20 formula", pkg.Version, err)
+	}
+	updated := journal.Entry{Op: "update", Package: "node", Before: "21.7.3", After: "22.3.0", Status: journal.StatusOK}
+	if _, _, err := undoPlan(ctx, lister, updated); err == nil {
+		t.Error("expected an error for a version no formula provides")
+	}
+	updated.Before, updated.After = "22.3.0", "23.0.0"
+	if _, _, err := undoPlan(ctx, lister, updated); err == nil || !strings.Contains(err.Error(), "cannot downgrade") {
+		t.Errorf("expected a downgrade error, got %v", err)
+	}
+}
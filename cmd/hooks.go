@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"voltig/config"
+	"voltig/internal/models"
+	"voltig/pkg/logger"
+)
+
+// hookError is a failed package hook. Running the hook again would most
+// likely fail the same way, so PkgOperation doesn't retry it.
+type hookError struct {
+	stage string
+	err   error
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %v", e.stage, e.err)
+}
+
+func (e *hookError) Unwrap() error { return e.err }
+
+func (e *hookError) Retryable() (bool, string) {
+	return false, e.stage + " hook failed"
+}
+
+/*
+withHooks wraps opFunc, a manager operation for manager.PkgOperation, with the
+package's pre_<op> and post_<op> hooks. A failing pre hook aborts the
+operation; a failing post hook is logged, as the package has already been
+installed or removed. When opFunc is retried, the pre hooks don't run again.
+*/
+func withHooks(cfg *config.PackageConfig, op string, opFunc func(context.Context, models.Package, func(string)) error) func(context.Context, models.Package, func(string)) error {
+	preRan := make(map[string]bool)
+	return func(ctx context.Context, pkg models.Package, showOutput func(string)) error {
+		if !preRan[pkg.Name[0]] {
+			if err := runHooks(ctx, cfg, "pre_"+op, pkg, showOutput); err != nil {
+				return err
+			}
+			preRan[pkg.Name[0]] = true
+		}
+		if err := opFunc(ctx, pkg, showOutput); err != nil {
+			return err
+		}
+		if err := runHooks(ctx, cfg, "post_"+op, pkg, showOutput); err != nil {
+			logger.Warn("Package hook failed", "package", strings.Join(pkg.Name, ", "), "error", err)
+		}
+		return nil
+	}
+}
+
+/*
+runHooks runs the package's hooks for stage in order, through the shell like
+custom commands, from the config directory. VOLTIG_PACKAGE, VOLTIG_PACKAGES,
+VOLTIG_VERSION, VOLTIG_MANAGER and VOLTIG_HOOK describe the package and stage.
+Output goes to showOutput when it is set. It stops at the first failure.
+*/
+func runHooks(ctx context.Context, cfg *config.PackageConfig, stage string, pkg models.Package, showOutput func(string)) error {
+	version := pkg.Version
+	if version == "" {
+		version = "latest"
+	}
+	env := []string{
+		"VOLTIG_HOOK=" + stage,
+		"VOLTIG_PACKAGE=" + pkg.Name[0],
+		"VOLTIG_PACKAGES=" + strings.Join(pkg.Name, " "),
+		"VOLTIG_VERSION=" + version,
+		"VOLTIG_MANAGER=" + pkg.Manager,
+	}
+	for _, line := range pkg.Hooks[stage] {
+		hook := buildCustomCommand(ctx, config.CustomCommand{Command: line, WorkDir: ".", Environment: env}, cfg, nil)
+		if showOutput != nil {
+			hook.Stdout = funcWriter(showOutput)
+			hook.Stderr = funcWriter(showOutput)
+			hook.Stdin = nil
+		}
+		if err := hook.Run(); err != nil {
+			return &hookError{stage: stage, err: fmt.Errorf("%s: %w", line, err)}
+		}
+	}
+	return nil
+}
+
+// funcWriter passes everything written to it to a function, such as
+// PkgOperation's progress output.
+type funcWriter func(string)
+
+func (f funcWriter) Write(p []byte) (int, error) {
+	f(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+)
+
+func TestWithHooks(t *testing.T) {
+	setTestLogger(t)
+	dir := t.TempDir()
+	cfg := &config.PackageConfig{Path: filepath.Join(dir, "voltig.yml")}
+	m := &fakeManager{installed: map[string]string{}}
+	pkg := models.Package{Name: []string{"node", "npm"}, Manager: "brew", Version: "^20", Hooks: map[string][]string{
+		"pre_install":  {"echo pre >> hooks.log"},
+		"post_install": {`echo "$VOLTIG_HOOK $VOLTIG_PACKAGE $VOLTIG_PACKAGES $VOLTIG_VERSION $VOLTIG_MANAGER" >> hooks.log`},
+	}}
+
+	var output []string
+	install := withHooks(cfg, manager.OpInstall, m.Install)
+	if err := install(context.Background(), pkg, func(s string) { output = append(output, s) }); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hooks.log"))
+	if err != nil {
+		t.Fatalf("hooks didn't run in the config directory: %v", err)
+	}
+	if want := "pre\npost_install node node npm ^20 brew\n"; string(data) != want {
+		t.Errorf("got hook log %q, want %q", data, want)
+	}
+	if strings.Join(m.ops, ", ") != "install node" {
+		t.Errorf("unexpected operations: %v", m.ops)
+	}
+
+	pkg.Hooks = map[string][]string{"pre_remove": {"echo refusing; exit 3"}}
+	err = withHooks(cfg, manager.OpRemove, m.Remove)(context.Background(), pkg, func(s string) { output = append(output, s) })
+	var hookErr *hookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected a hook error, got %v", err)
+	}
+	if retry, _ := hookErr.Retryable(); retry {
+		t.Error("failed hooks must not be retried")
+	}
+	if _, ok := m.installed["node"]; !ok || len(m.ops) != 1 {
+		t.Errorf("failing pre hook must abort the remove, ops: %v", m.ops)
+	}
+	if !strings.Contains(strings.Join(output, ""), "refusing") {
+		t.Errorf("hook output not shown: %q", output)
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"voltig/internal/models"
+	"voltig/internal/scaffold"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	initYes      bool
+	initForce    bool
+	initFromScan bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a voltig.yml for the current project",
+	Long: `Detect the project type (go.mod, package.json, Cargo.toml, pyproject.toml,
+Makefile), propose packages and build/test/check commands for it, and write a
+commented voltig.yml. Use --yes to accept every proposal without prompting.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			logger.Error("Failed to get working directory", "error", err)
+			os.Exit(1)
+		}
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, assumeYes: initYes}
+		scan := func() (string, []models.PackageStatus, error) { return scanSystemPackages(cmd.Context()) }
+		if err := runInit(p, cwd, configFile, scan); err != nil {
+			logger.Error("Init failed", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runInit performs voltig init in dir, writing the config to path. scan is
+// used to seed packages from the current machine when requested.
+func runInit(p *prompter, dir, path string, scan func() (string, []models.PackageStatus, error)) error {
+	if _, err := os.Stat(path); err == nil && !initForce {
+		if p.assumeYes || !p.confirm(path+" already exists. Overwrite?", false) {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+	}
+
+	projects := scaffold.Detect(dir)
+	if len(projects) == 0 {
+		logger.Info("No known project type detected")
+	}
+	for _, proj := range projects {
+		logger.Info("Detected project", "type", proj.Kind, "marker", proj.Marker)
+	}
+	proposed := scaffold.Propose(dir, projects)
+
+	plan := scaffold.Plan{Projects: proposed.Projects}
+	for _, pkg := range proposed.Packages {
+		if p.confirm(fmt.Sprintf("Add package %s (%s)?", pkg.Name, pkg.Comment), true) {
+			plan.Packages = append(plan.Packages, pkg)
+		}
+	}
+	for _, c := range proposed.Commands {
+		if p.confirm(fmt.Sprintf("Add command %q running `%s`?", c.Name, c.Command), true) {
+			plan.Commands = append(plan.Commands, c)
+		}
+	}
+
+	if initFromScan || (!p.assumeYes && p.confirm("Seed packages from the packages installed on this machine?", false)) {
+		managerName, found, err := scan()
+		if err != nil {
+			logger.Warn("Failed to scan installed packages", "error", err)
+		} else {
+			plan.Packages = appendScanned(plan.Packages, managerName, found)
+			logger.Info("Seeded packages from scan", "manager", managerName, "count", len(found))
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(scaffold.Render(plan)), 0o644); err != nil {
+		return err
+	}
+	logger.Info("Created config", "path", path, "packages", len(plan.Packages), "commands", len(plan.Commands))
+	logger.Info("Next steps: review the file, then run `voltig lint` and `voltig install`")
+	return nil
+}
+
+// appendScanned adds scanned packages not already proposed, recording the
+// installed version in a comment and tracking latest.
+func appendScanned(pkgs []scaffold.PackageProposal, managerName string, found []models.PackageStatus) []scaffold.PackageProposal {
+	existing := make(map[string]bool)
+	for _, pkg := range pkgs {
+		existing[pkg.Name] = true
+	}
+	for _, s := range found {
+		if existing[s.Name] {
+			continue
+		}
+		existing[s.Name] = true
+		pkgs = append(pkgs, scaffold.PackageProposal{
+			Name:    s.Name,
+			Manager: managerName,
+			Version: "latest",
+			Comment: "installed: " + s.Version.Raw,
+		})
+	}
+	return pkgs
+}
+
+// prompter asks yes/no questions, or accepts every default when assumeYes is set.
+type prompter struct {
+	in        *bufio.Reader
+	out       io.Writer
+	assumeYes bool
+}
+
+// confirm asks question and returns the answer, or def on empty input or --yes.
+func (p *prompter) confirm(question string, def bool) bool {
+	if p.assumeYes {
+		return def
+	}
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	_, _ = fmt.Fprintf(p.out, "%s %s ", question, hint)
+	line, err := p.in.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	if answer == "" {
+		if err != nil && err != io.EOF {
+			logger.Warn("Failed to read answer", "error", err)
+		}
+		return def
+	}
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Accept all proposals without prompting")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing config file")
+	initCmd.Flags().BoolVar(&initFromScan, "from-scan", false, "Seed packages from the packages installed on this machine")
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/models"
+)
+
+func TestRunInit_Yes(t *testing.T) {
+	setTestLogger(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "voltig.yml")
+	scan := func() (string, []models.PackageStatus, error) {
+		return "brew", []models.PackageStatus{{Name: "go", Version: models.ParseVersion("1.22", "brew")}, {Name: "jq", Version: models.ParseVersion("1.7", "brew")}}, nil
+	}
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: io.Discard, assumeYes: true}
+
+	initFromScan = true
+	defer func() { initFromScan = false }()
+	if err := runInit(p, dir, path, scan); err != nil {
+		t.Fatalf("runInit failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{"name: go\n", "name: jq\n", "# installed: 1.7", "command: go test ./..."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in generated config:\n%s", want, out)
+		}
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("generated config does not load: %v", err)
+	}
+	if _, err := config.ValidateCommands(cfg.Commands); err != nil {
+		t.Errorf("generated commands clash with core commands: %v", err)
+	}
+	if _, ok := cfg.Commands["check"]; !ok {
+		t.Errorf("expected a check command, got %v", sortedKeys(cfg.Commands))
+	}
+	if strings.Count(out, "name: go\n") != 1 {
+		t.Errorf("expected scanned go to be deduplicated:\n%s", out)
+	}
+
+	// A second non-interactive run must not overwrite without --force
+	if err := runInit(p, dir, path, scan); err == nil {
+		t.Errorf("expected error when config exists")
+	}
+}
+
+func TestRunInit_Interactive(t *testing.T) {
+	setTestLogger(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "voltig.yml")
+	// rust package: yes; build: no; test: default; check: yes; seed from scan: default (no)
+	answers := "y\nn\n\nyes\n\n"
+	p := &prompter{in: bufio.NewReader(strings.NewReader(answers)), out: io.Discard}
+	scan := func() (string, []models.PackageStatus, error) { return "", nil, errors.New("should not scan") }
+	if err := runInit(p, dir, path, scan); err != nil {
+		t.Fatalf("runInit failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	if strings.Contains(out, "cargo build") || !strings.Contains(out, "cargo test") || !strings.Contains(out, "cargo clippy") {
+		t.Errorf("answers not applied:\n%s", out)
+	}
+}
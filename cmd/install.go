@@ -1,110 +1,419 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"voltig/config"
+	"voltig/internal/lock"
 	"voltig/internal/manager"
 	"voltig/internal/models"
+	"voltig/internal/state"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	installFrozen    bool
+	installReinstall bool
+	installMinimal   bool
+	installWith      []string
+	installAtomic    bool
+)
+
 var installCmd = &cobra.Command{
 	Use:     "install [pkg_name...]",
 	Aliases: []string{"i"},
 	Short:   "Install specific or all packages",
-	Args:    cobra.MinimumNArgs(0),
-	Run: func(_ *cobra.Command, args []string) {
-		fmt.Println(HeaderStyle.Render("🔧 Voltig: Installing Packages"))
+	Long: `Install specific or all packages and record the resolved versions in
+voltig.lock. With --frozen, install exactly the locked versions and fail if
+voltig.yml and voltig.lock disagree.
+
+With --atomic, a failed required package rolls the run back: every package it
+newly installed is removed again, in reverse order. Packages that were already
+installed before the run are never removed.`,
+	Args: cobra.MinimumNArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		if code := runInstall(cmd.Context(), args); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
 
+// runInstall installs args (or every package in scope) and returns the exit code.
+func runInstall(ctx context.Context, args []string) int {
+	fmt.Println(HeaderStyle.Render("🔧 Voltig: Installing Packages"))
+
+	// Don't install Homebrew just to print a plan
+	if !dryRun {
 		if err := ensureHomebrew(); err != nil {
 			fmt.Println(ErrorStyle.Render("Failed to install Homebrew:", err.Error()))
-			os.Exit(1)
+			return 1
 		}
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		logger.Error("Failed to load config", "error", err)
+		return 1
+	}
+	m := manager.ForOS()
+	if m == nil {
+		logger.Error("No supported package manager found for this OS")
+		return 1
+	}
+	pkgs, err := profilePackages(cfg, args)
+	if err != nil {
+		logger.Error("Failed to resolve profile", "error", err)
+		return 1
+	}
+
+	var (
+		failedInstalls    []string
+		successInstalls   []string
+		cancelledInstalls []string
+	)
+
+	// Determine which packages to install
+	if len(args) == 0 {
+		logger.Info("Installing all packages from config", "count", len(pkgs))
+	} else {
+		logger.Info("Installing specified packages", "packages", args)
+	}
+	sel := selectPackages(pkgs, args)
+	targetPkgs, notFound := sel.Targets, sel.NotFound
+	// Packages named explicitly are installed even if optional
+	var skippedOptional []config.Package
+	if len(args) == 0 {
+		targetPkgs, skippedOptional = filterOptional(targetPkgs, installMinimal, installWith)
+	}
+
+	lf, err := lock.Load(lockPath(cfg))
+	if err != nil {
+		logger.Error("Failed to load lockfile", "error", err)
+		return 1
+	}
+	if installFrozen {
+		if !lf.Exists() {
+			logger.Error("--frozen requires a lockfile; run `voltig lock` first", "path", displayPath(lf.Path))
+			return 1
+		}
+		if problems := lf.Verify(targetPkgs, cfg.Packages); len(problems) > 0 {
+			for _, p := range problems {
+				logger.Error("Lockfile out of date", "problem", p)
+			}
+			logger.Error("Run `voltig lock --update` to refresh voltig.lock")
+			return 1
+		}
+		// Names locked at different versions are installed separately
+		targetPkgs = splitByLock(lf, targetPkgs)
+	}
 
-		cfg, err := config.LoadConfig(configFile)
+	// Install packages, skipping those already present at a satisfying version
+	var pkgModels []models.Package
+	var unresolved, alreadyInstalled []string
+	// Only packages missing beforehand count as installed by voltig
+	missing := make(map[string]bool)
+	for _, pkg := range targetPkgs {
+		model := models.ToModel(pkg)
+		model.Reinstall = installReinstall
+		if installFrozen {
+			if model, err = lockedPackage(lf, model); err != nil {
+				logger.Error("Cannot install locked version", "error", err)
+				unresolved = append(unresolved, pkg.Name...)
+				continue
+			}
+		}
+		statuses, satisfied, err := manager.Satisfied(ctx, m, model)
 		if err != nil {
-			logger.Error("Failed to load config", "error", err)
-			os.Exit(1)
-		}
-		m := manager.ForOS()
-		if m == nil {
-			logger.Error("No supported package manager found for this OS")
-			os.Exit(1)
-		}
-
-		var (
-			failedInstalls  []string
-			successInstalls []string
-			notFound        []string
-			targetPkgs      []config.Package
-		)
-
-		// Determine which packages to install
-		if len(args) == 0 {
-			// Install all packages from config
-			targetPkgs = cfg.Packages
-			logger.Info("Installing all packages from config", "count", len(targetPkgs))
-		} else {
-			// Install only specified packages
-			logger.Info("Installing specified packages", "packages", args)
-			for _, arg := range args {
-				found := false
-				for _, pkg := range cfg.Packages {
-					// Check if the package name is in the list of names
-					for _, name := range pkg.Name {
-						if name == arg {
-							targetPkgs = append(targetPkgs, pkg)
-							found = true
-							break
-						}
-					}
-					if found {
-						break
-					}
-				}
-				if !found {
-					logger.Error("Package not found in config", "package", arg)
-					notFound = append(notFound, arg)
-				}
+			logger.Warn("Failed to check package status", "package", pkg.Name[0], "error", err)
+		}
+		if satisfied && !installReinstall {
+			var versions []string
+			for _, status := range statuses {
+				versions = append(versions, status.Version.String())
+			}
+			logger.Info("Package already installed", "package", strings.Join(pkg.Name, ", "), "version", strings.Join(versions, ", "))
+			alreadyInstalled = append(alreadyInstalled, pkg.Name...)
+			continue
+		}
+		missing[pkg.Name[0]] = len(statuses) > 0
+		for _, status := range statuses {
+			missing[pkg.Name[0]] = missing[pkg.Name[0]] && status.Status == "missing"
+		}
+		pick := manager.PickVersion
+		if installFrozen {
+			pick = manager.LockedVersion
+		}
+		if model, err = pick(ctx, m, model); err != nil {
+			logger.Error("Failed to resolve version", "package", pkg.Name[0], "error", err)
+			unresolved = append(unresolved, pkg.Name...)
+			continue
+		}
+		pkgModels = append(pkgModels, model)
+	}
+	if ctx.Err() != nil {
+		logger.Warn("Install cancelled before any package was installed")
+		return exitCancelled
+	}
+	if dryRun {
+		if len(alreadyInstalled) > 0 {
+			logger.Info("Already installed packages", "packages", alreadyInstalled)
+		}
+		return printDryRun(ctx, m, manager.OpInstall, pkgModels, append(notFound, unresolved...))
+	}
+
+	var before map[string]bool
+	if installAtomic {
+		if required, _ := splitOptional(targetPkgs, unresolved); len(required) > 0 || len(notFound) > 0 {
+			logger.Error("Not installing anything with --atomic: some packages can't be installed", "packages", append(notFound, required...))
+			return 1
+		}
+		before = installedNames(ctx, m, pkgModels)
+	}
+
+	done := journalOp(ctx, m, manager.OpInstall, pkgModels, 0)
+	successInstalls, failedInstalls, cancelledInstalls = manager.PkgOperation(ctx, "Installing", "installed", pkgModels, withHooks(cfg, manager.OpInstall, m.Install))
+	done(successInstalls, failedInstalls, cancelledInstalls)
+	failedInstalls = append(failedInstalls, unresolved...)
+	failedInstalls, failedOptional := splitOptional(targetPkgs, failedInstalls)
+
+	var rolledBack, rollbackFailed []string
+	if installAtomic && (len(failedInstalls) > 0 || len(cancelledInstalls) > 0) {
+		logger.Warn("Rolling back packages installed by this run")
+		// Roll back even when interrupted; a second Ctrl-C still exits at once
+		rolledBack, rollbackFailed = rollback(context.WithoutCancel(ctx), m, pkgModels, before)
+		successInstalls = without(successInstalls, rolledBack)
+	}
+	recordOwnership(cfg, pkgModels, successInstalls, missing)
+
+	if !installFrozen {
+		// Lock already-installed packages too, so the lock covers the whole config
+		toLock := append([]string{}, successInstalls...)
+		for _, name := range alreadyInstalled {
+			if _, locked := lf.Get(name); !locked {
+				toLock = append(toLock, name)
+			}
+		}
+		if len(toLock) > 0 {
+			// Record what did get installed even if the run was interrupted
+			updateLock(context.WithoutCancel(ctx), lf, m, targetPkgs, toLock)
+		}
+	}
+
+	// Print summary
+	if len(successInstalls) > 0 {
+		logger.Info("Successfully installed packages", "packages", successInstalls)
+	}
+	if len(alreadyInstalled) > 0 {
+		logger.Info("Already installed packages", "packages", alreadyInstalled)
+	}
+	if len(failedInstalls) > 0 {
+		logger.Error("Failed to install packages", "packages", failedInstalls)
+	}
+	if len(failedOptional) > 0 {
+		logger.Warn("Failed to install optional packages", "packages", failedOptional)
+	}
+	reportCancelled(ctx, cancelledInstalls)
+	if len(rolledBack) > 0 {
+		logger.Warn("Rolled back packages", "packages", rolledBack)
+	}
+	if len(rollbackFailed) > 0 {
+		logger.Error("Failed to roll back packages; remove them manually", "packages", rollbackFailed)
+	}
+	if len(sel.Skipped) > 0 {
+		logger.Info("Skipped packages (platform)", "packages", packageNames(sel.Skipped))
+	}
+	if len(skippedOptional) > 0 {
+		logger.Info("Skipped optional packages (--minimal)", "packages", packageNames(skippedOptional))
+	}
+	if len(notFound) > 0 {
+		logger.Error("Packages not found in config", "packages", notFound)
+	}
+
+	// Exit with error code if any failures
+	if len(cancelledInstalls) > 0 {
+		return exitCancelled
+	} else if len(failedInstalls) > 0 || len(notFound) > 0 {
+		return 1
+	} else if len(failedOptional) > 0 {
+		logger.Warn("Required packages installed; some optional packages failed")
+	} else if len(successInstalls) > 0 {
+		logger.Info("All requested packages installed successfully")
+	} else if len(alreadyInstalled) > 0 {
+		logger.Info("All requested packages were already installed")
+	} else {
+		logger.Info("No packages were installed")
+	}
+	return 0
+}
+
+/*
+lockedPackage sets the version of pkg to the one locked in lf for its names,
+normalised so it works as a constraint: Homebrew revisions such as the _1 of
+1.7.1_1 are dropped. manager.LockedVersion then maps it to what the manager
+can install. Names locked at different versions must be installed separately,
+see splitByLock.
+*/
+func lockedPackage(lf *lock.File, pkg models.Package) (models.Package, error) {
+	var version string
+	for i, name := range pkg.Name {
+		entry, ok := lf.Get(name)
+		if !ok {
+			return pkg, fmt.Errorf("%s is not in %s; run `voltig lock --update`", name, lock.FileName)
+		}
+		if i > 0 && entry.Version != version {
+			return pkg, fmt.Errorf("%s and %s are locked at different versions", pkg.Name[0], name)
+		}
+		version = entry.Version
+	}
+	pkg.Version = models.ParseVersion(version, "").String()
+	return pkg, nil
+}
+
+/*
+splitByLock splits each package of pkgs into one package per version locked in
+lf for its names, keeping the order of the names. Names that share a version
+stay together, so their hooks run once.
+*/
+func splitByLock(lf *lock.File, pkgs []config.Package) []config.Package {
+	var split []config.Package
+	for _, pkg := range pkgs {
+		var versions []string
+		names := make(map[string]config.PackageName)
+		for _, name := range pkg.Name {
+			entry, _ := lf.Get(name)
+			if _, ok := names[entry.Version]; !ok {
+				versions = append(versions, entry.Version)
 			}
+			names[entry.Version] = append(names[entry.Version], name)
+		}
+		for _, version := range versions {
+			part := pkg
+			part.Name = names[version]
+			split = append(split, part)
 		}
+	}
+	return split
+}
 
-		// Install packages
-		var pkgModels []models.Package
-		for _, pkg := range targetPkgs {
-			pkgModels = append(pkgModels, models.ToModel(pkg))
+// installedNames returns which names of pkgs are installed, by any means,
+// before the run. Names whose status can't be read count as installed, so
+// a rollback never removes them.
+func installedNames(ctx context.Context, m manager.PackageManager, pkgs []models.Package) map[string]bool {
+	installed := make(map[string]bool)
+	for _, pkg := range pkgs {
+		statuses, err := m.GetStatus(ctx, pkg)
+		for _, name := range pkg.Name {
+			installed[name] = err != nil
 		}
-		successInstalls, failedInstalls = manager.PkgOperation("Installing", "installed", pkgModels, m.Install)
+		for _, status := range statuses {
+			installed[status.Name] = installed[status.Name] || strings.HasPrefix(status.Status, "installed")
+		}
+	}
+	return installed
+}
 
-		// Print summary
-		if len(successInstalls) > 0 {
-			logger.Info("Successfully installed packages", "packages", successInstalls)
+/*
+rollback removes, newest first, every name of pkgs that was missing in the
+before snapshot and is installed now, including names of multi-name packages
+that failed halfway. It returns the names removed and those it failed to
+remove.
+*/
+func rollback(ctx context.Context, m manager.PackageManager, pkgs []models.Package, before map[string]bool) (removed, failed []string) {
+	var toRemove []models.Package
+	for i := len(pkgs) - 1; i >= 0; i-- {
+		for j := len(pkgs[i].Name) - 1; j >= 0; j-- {
+			name := pkgs[i].Name[j]
+			if installed, known := before[name]; installed || !known {
+				continue
+			}
+			single := pkgs[i]
+			single.Name = []string{name}
+			if statuses, err := m.GetStatus(ctx, single); err != nil || len(statuses) == 0 || !strings.HasPrefix(statuses[0].Status, "installed") {
+				continue
+			}
+			toRemove = append(toRemove, single)
 		}
-		if len(failedInstalls) > 0 {
-			logger.Error("Failed to install packages", "packages", failedInstalls)
+	}
+	done := journalOp(ctx, m, manager.OpRemove, toRemove, 0)
+	removed, failed, _ = manager.PkgOperation(ctx, "Rolling back", "rolled back", toRemove, m.Remove)
+	done(removed, failed, nil)
+	return removed, failed
+}
+
+// without returns names minus those in drop.
+func without(names, drop []string) []string {
+	dropped := make(map[string]bool)
+	for _, name := range drop {
+		dropped[name] = true
+	}
+	var kept []string
+	for _, name := range names {
+		if !dropped[name] {
+			kept = append(kept, name)
 		}
-		if len(notFound) > 0 {
-			logger.Error("Packages not found in config", "packages", notFound)
+	}
+	return kept
+}
+
+// recordOwnership marks the installed packages that were missing before as
+// installed by voltig, so `voltig sync --prune` may remove them later.
+func recordOwnership(cfg *config.PackageConfig, pkgs []models.Package, installed []string, missing map[string]bool) {
+	if len(missing) == 0 {
+		return
+	}
+	st, err := state.Load(state.DefaultPath())
+	if err != nil {
+		logger.Warn("Failed to load state", "error", err)
+		return
+	}
+	done := make(map[string]bool)
+	for _, name := range installed {
+		done[name] = true
+	}
+	for _, pkg := range pkgs {
+		if done[pkg.Name[0]] && missing[pkg.Name[0]] {
+			st.Own(pkg.Manager, pkg.Name, pkg.Version, cfg.Path)
 		}
+	}
+	if err := st.Save(); err != nil {
+		logger.Warn("Failed to save state", "path", st.Path, "error", err)
+	}
+}
 
-		// Exit with error code if any failures
-		if len(failedInstalls) > 0 || len(notFound) > 0 {
-			os.Exit(1)
-		} else if len(successInstalls) > 0 {
-			logger.Info("All requested packages installed successfully")
-		} else {
-			logger.Info("No packages were installed")
+// updateLock records the resolved versions of the installed packages.
+func updateLock(ctx context.Context, lf *lock.File, m manager.PackageManager, pkgs []config.Package, installed []string) {
+	done := make(map[string]bool)
+	for _, name := range installed {
+		done[name] = true
+	}
+	for _, pkg := range pkgs {
+		if !done[pkg.Name[0]] {
+			continue
 		}
-	},
+		if err := lockPackage(ctx, lf, m, pkg); err != nil {
+			logger.Warn("Failed to resolve installed version", "package", pkg.Name[0], "error", err)
+		}
+	}
+	if err := lf.Save(); err != nil {
+		logger.Warn("Failed to write lockfile", "path", lf.Path, "error", err)
+	}
 }
 
 func init() {
+	installCmd.Flags().BoolVar(&installFrozen, "frozen", false, "Install exactly the versions in voltig.lock")
+	installCmd.Flags().BoolVar(&installReinstall, "reinstall", false, "Install packages even if already installed")
+	installCmd.Flags().BoolVar(&installMinimal, "minimal", false, "Skip optional packages")
+	installCmd.Flags().StringSliceVar(&installWith, "with", nil, "With --minimal, still install these optional packages (\""+optionalAll+"\" for all)")
+	installCmd.Flags().BoolVar(&installAtomic, "atomic", false, "Remove the packages this run installed if a required package fails")
+	addDryRunFlag(installCmd)
+	addProfileFlag(installCmd)
 	rootCmd.AddCommand(installCmd)
 }
 
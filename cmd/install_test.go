@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"voltig/config"
+	"voltig/internal/lock"
+	"voltig/internal/manager"
 	"voltig/internal/models"
 	"voltig/pkg/logger"
 )
@@ -127,3 +131,89 @@ func runInstallCmd(cfg *config.PackageConfig, m *mockManager, args []string) int
 	}
 	return 0
 }
+
+func TestRollback(t *testing.T) {
+	setTestLogger(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	ctx := context.Background()
+	m := &fakeManager{installed: map[string]string{"node": "20.0.0"}}
+	pkgs := []models.Package{
+		{Name: []string{"node"}, Manager: "brew"},
+		{Name: []string{"jq"}, Manager: "brew"},
+		{Name: []string{"rg", "fd"}, Manager: "brew"},
+	}
+	before := installedNames(ctx, m, pkgs)
+	// The run reinstalled node, installed jq and got halfway through rg, fd
+	m.installed["jq"] = "1.7"
+	m.installed["rg"] = "14.0"
+
+	removed, failed := rollback(ctx, m, pkgs, before)
+	if strings.Join(removed, ",") != "rg,jq" || len(failed) != 0 {
+		t.Errorf("rollback removed=%v failed=%v, want rg,jq newest first", removed, failed)
+	}
+	if _, ok := m.installed["node"]; !ok {
+		t.Error("package installed before the run must not be rolled back")
+	}
+	if got := without([]string{"node", "jq", "rg"}, removed); strings.Join(got, ",") != "node" {
+		t.Errorf("without = %v", got)
+	}
+}
+
+// listingFake is a fakeManager that can list available versions.
+type listingFake struct {
+	*fakeManager
+	versions map[string][]string
+}
+
+func (m *listingFake) Versions(_ context.Context, pkg models.Package) ([]string, error) {
+	return m.versions[pkg.Name[0]], nil
+}
+
+func TestFrozenVersions(t *testing.T) {
+	setTestLogger(t)
+	lf := &lock.File{Packages: []lock.Entry{
+		{Name: "node", Manager: "brew", Constraint: "^20", Version: "20.11.1_1"},
+		{Name: "jq", Manager: "brew", Version: "1.7.1"},
+	}}
+	m := &listingFake{fakeManager: &fakeManager{installed: map[string]string{"node": "20.11.1"}}, versions: map[string][]string{
+		"node": {"22.3.0", "20"},
+		"jq":   {"1.7.1"},
+	}}
+	ctx := context.Background()
+
+	node, err := lockedPackage(lf, models.Package{Name: []string{"node"}, Manager: "brew", Version: "^20"})
+	if err != nil || node.Version != "20.11.1" {
+		t.Fatalf("got %q, %v; want the locked version without its revision", node.Version, err)
+	}
+	if _, satisfied, err := manager.Satisfied(ctx, m, node); err != nil || !satisfied {
+		t.Errorf("installed locked version not satisfied: %v", err)
+	}
+	if node, err = manager.LockedVersion(ctx, m, node); err != nil || node.Version != "20" {
+		t.Errorf("got %q, %v; want the node@20 formula", node.Version, err)
+	}
+
+	jq, _ := lockedPackage(lf, models.Package{Name: []string{"jq"}, Manager: "brew"})
+	if _, satisfied, _ := manager.Satisfied(ctx, m, jq); satisfied {
+		t.Error("missing package reported as satisfied")
+	}
+	if jq, err = manager.LockedVersion(ctx, m, jq); err != nil || jq.Version != "latest" {
+		t.Errorf("got %q, %v; want latest for the stable version", jq.Version, err)
+	}
+
+	if _, err := lockedPackage(lf, models.Package{Name: []string{"go"}, Manager: "brew"}); err == nil || !strings.Contains(err.Error(), "not in voltig.lock") {
+		t.Errorf("expected an error for an unlocked package, got %v", err)
+	}
+
+	lf.Set(lock.Entry{Name: "rust", Manager: "brew", Constraint: "^20", Version: "1.79.0"})
+	lf.Set(lock.Entry{Name: "npm", Manager: "brew", Constraint: "^20", Version: "20.11.1_1"})
+	split := splitByLock(lf, []config.Package{{Name: config.PackageName{"node", "rust", "npm"}, Manager: "brew", Version: "^20"}})
+	if len(split) != 2 || strings.Join(split[0].Name, " ") != "node npm" || strings.Join(split[1].Name, " ") != "rust" {
+		t.Fatalf("names not split by locked version: %v", split)
+	}
+	if rust, err := lockedPackage(lf, models.ToModel(split[1])); err != nil || rust.Version != "1.79.0" {
+		t.Errorf("got %q, %v; want rust's own locked version", rust.Version, err)
+	}
+	if _, err := lockedPackage(lf, models.Package{Name: []string{"node", "rust"}, Manager: "brew"}); err == nil || !strings.Contains(err.Error(), "different versions") {
+		t.Errorf("expected an error for names locked at different versions, got %v", err)
+	}
+}
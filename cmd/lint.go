@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"voltig/config"
+	"voltig/internal/semver"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Lint and validate your voltig.yml config file",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		cfg, diags, err := config.LoadConfigDiagnostics(configFile)
+		if err != nil {
+			logger.Error("Config error", "error", err)
+			os.Exit(1)
+		}
+		diags = append(diags, lintConfig(cfg)...)
+		diags.Sort()
+		printDiagnostics(diags)
+		if diags.HasErrors() {
+			os.Exit(1)
+		}
+		logger.Info("Config validation successful")
+	},
+}
+
+// lintConfig runs the checks that need the merged config: required fields,
+// protected command names, profiles, unresolved variables and env files.
+func lintConfig(cfg *config.PackageConfig) config.Diagnostics {
+	var diags config.Diagnostics
+	errorAt := func(key, format string, args ...interface{}) {
+		diags = append(diags, config.Diagnostic{Position: cfg.PositionOf(key), Severity: config.SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, pkg := range cfg.Packages {
+		if len(pkg.Name) > 0 && pkg.Manager == "" {
+			errorAt("packages."+pkg.Name[0], "package %q is missing required field \"manager\"", strings.Join(pkg.Name, ", "))
+		}
+		if len(pkg.Name) > 0 {
+			if _, err := semver.ParseConstraint(pkg.Version); err != nil {
+				errorAt("packages."+pkg.Name[0], "package %q: %v", pkg.Name[0], err)
+			}
+			if d, err := time.ParseDuration(pkg.Timeout); pkg.Timeout != "" && (err != nil || d <= 0) {
+				errorAt("packages."+pkg.Name[0], "package %q: invalid timeout %q, expected a duration such as 10m", pkg.Name[0], pkg.Timeout)
+			}
+			// Inherited top-level values are checked once below
+			key := "packages." + pkg.Name[0]
+			if pkg.Retries != nil && *pkg.Retries < 0 && !cfg.Inherited(key+".retries") {
+				errorAt(key, "package %q: retries must not be negative", pkg.Name[0])
+			}
+			if d, err := time.ParseDuration(pkg.RetryBackoff); pkg.RetryBackoff != "" && !cfg.Inherited(key+".retry_backoff") && (err != nil || d < 0) {
+				errorAt(key, "package %q: invalid retry_backoff %q, expected a duration such as 5s", pkg.Name[0], pkg.RetryBackoff)
+			}
+		}
+	}
+	if cfg.Retries < 0 {
+		errorAt("retries", "retries must not be negative")
+	}
+	if d, err := time.ParseDuration(cfg.RetryBackoff); cfg.RetryBackoff != "" && (err != nil || d < 0) {
+		errorAt("retry_backoff", "invalid retry_backoff %q, expected a duration such as 5s", cfg.RetryBackoff)
+	}
+	// Check for protected command overrides and namespace conflicts
+	var invalid bool
+	for _, name := range sortedKeys(cfg.Commands) {
+		if _, err := config.ValidateCommands(map[string]config.CustomCommand{name: cfg.Commands[name]}); err != nil {
+			errorAt("commands."+name, "%v", err)
+			invalid = true
+		}
+	}
+	if !invalid {
+		if _, err := config.ValidateCommands(cfg.Commands); err != nil {
+			errorAt("commands", "%v", err)
+		}
+	}
+	// Check profile references and inheritance
+	if err := cfg.ValidateProfiles(); err != nil {
+		if pd, ok := err.(config.Diagnostics); ok {
+			diags = append(diags, pd...)
+		}
+	}
+	// Check for unresolved ${VAR} references and missing env files
+	refs := append([]config.UnresolvedRef{}, cfg.Unresolved...)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Field < refs[j].Field })
+	for _, ref := range refs {
+		errorAt(ref.Field, "unresolved variable ${%s} in %s", ref.Name, ref.Field)
+	}
+	for _, envFile := range cfg.EnvFile {
+		if _, err := os.Stat(envFile); err != nil {
+			diags = append(diags, config.Diagnostic{Position: cfg.PositionOf("env_file"), Severity: config.SeverityWarning, Message: "env file not found: " + displayPath(envFile)})
+		}
+	}
+	return diags
+}
+
+// printDiagnostics writes diagnostics compiler-style to stderr, followed by a count.
+func printDiagnostics(diags config.Diagnostics) {
+	var errs, warnings int
+	for _, d := range diags {
+		d.File = displayPath(d.File)
+		severity := WarningStyle.Render(string(d.Severity))
+		if d.Severity == config.SeverityError {
+			severity = ErrorStyle.Render(string(d.Severity))
+			errs++
+		} else {
+			warnings++
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s: %s\n", d.Position, severity, d.Message)
+	}
+	if len(diags) > 0 {
+		fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", errs, warnings)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
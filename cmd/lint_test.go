@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+)
+
+func TestLintConfig(t *testing.T) {
+	cfg := &config.PackageConfig{
+		Path:     "voltig.yml",
+		Packages: []config.Package{{Name: []string{"jq"}}, {Name: []string{"go"}, Manager: "brew", Version: "^1.2,,"}},
+		Commands: map[string]config.CustomCommand{
+			"install": {Command: "echo"},
+			"build":   {Command: "go build"},
+		},
+		Profiles:   map[string]config.Profile{"x": {Extends: []string{"missing"}}},
+		Unresolved: []config.UnresolvedRef{{Field: "commands.build.command", Name: "OUT"}},
+		EnvFile:    []string{"/nonexistent/.env"},
+	}
+	diags := lintConfig(cfg)
+	var errs, warnings []string
+	for _, d := range diags {
+		if d.Severity == config.SeverityError {
+			errs = append(errs, d.Message)
+		} else {
+			warnings = append(warnings, d.Message)
+		}
+	}
+	all := strings.Join(errs, "\n")
+	for _, want := range []string{`"jq" is missing required field "manager"`, `"install" is protected`, `unknown profile "missing"`, "${OUT}", `"go": invalid version constraint`} {
+		if !strings.Contains(all, want) {
+			t.Errorf("expected error containing %q, got:\n%s", want, all)
+		}
+	}
+	if len(errs) != 5 || len(warnings) != 1 {
+		t.Errorf("expected 5 errors and 1 warning, got %v / %v", errs, warnings)
+	}
+}
+
+func TestLintConfig_Retries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	if err := os.WriteFile(path, []byte(`retries: -1
+retry_backoff: soon
+packages:
+  - name: jq
+    manager: brew
+  - name: node
+    manager: brew
+    retries: -1
+    retry_backoff: soon
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := config.LoadConfigDiagnostics(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, d := range lintConfig(cfg) {
+		errs = append(errs, d.Message)
+	}
+	all := strings.Join(errs, "\n")
+	// Values equal to the defaults are still checked when set explicitly;
+	// jq's inherited ones are only reported once, at the top level
+	if len(errs) != 4 || strings.Contains(all, `"jq"`) || strings.Count(all, `"node"`) != 2 {
+		t.Errorf("unexpected errors:\n%s", all)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"voltig/config"
+	"voltig/internal/lock"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var lockUpdate bool
+
+var lockCmd = &cobra.Command{
+	Use:   "lock [pkg_name...]",
+	Short: "Write voltig.lock from the installed package versions",
+	Long: `Record the installed version, source and checksum of each package in
+voltig.lock. Packages already locked are kept as-is unless --update is given;
+entries for packages removed from voltig.yml are dropped.
+
+  voltig lock                # lock packages missing from voltig.lock
+  voltig lock --update       # refresh every entry
+  voltig lock --update node  # refresh only node`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		m := manager.ForOS()
+		if m == nil {
+			logger.Error("No supported package manager found for this OS")
+			os.Exit(1)
+		}
+		lf, err := lock.Load(lockPath(cfg))
+		if err != nil {
+			logger.Error("Failed to load lockfile", "error", err)
+			os.Exit(1)
+		}
+		if len(args) > 0 && !lockUpdate {
+			logger.Error("Package names require --update")
+			os.Exit(1)
+		}
+		if code := runLock(cmd.Context(), cfg, m, lf, args); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+// runLock resolves the selected packages into lf and saves it. Without args
+// only packages missing from the lock are resolved unless --update is set.
+func runLock(ctx context.Context, cfg *config.PackageConfig, m manager.PackageManager, lf *lock.File, args []string) int {
+	sel := selectPackages(cfg.Packages, args)
+	if len(sel.NotFound) > 0 {
+		return 1
+	}
+	var failed []string
+	for _, pkg := range sel.Targets {
+		if allLocked(lf, pkg) && !lockUpdate {
+			continue
+		}
+		if ctx.Err() != nil {
+			logger.Warn("Lock cancelled; keeping entries resolved so far")
+			break
+		}
+		if err := lockPackage(ctx, lf, m, pkg); err != nil {
+			logger.Error("Failed to lock package", "package", pkg.Name[0], "error", err)
+			failed = append(failed, pkg.Name[0])
+		}
+	}
+	if removed := lf.Prune(cfg.Packages); len(removed) > 0 {
+		logger.Info("Dropped lock entries no longer in config", "packages", removed)
+	}
+	if err := lf.Save(); err != nil {
+		logger.Error("Failed to write lockfile", "path", lf.Path, "error", err)
+		return 1
+	}
+	logger.Info("Wrote lockfile", "path", displayPath(lf.Path), "packages", len(lf.Packages))
+	if len(failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lockPath returns the voltig.lock belonging to the loaded project config.
+func lockPath(cfg *config.PackageConfig) string {
+	if cfg.Path != "" {
+		return lock.PathFor(cfg.Path)
+	}
+	return lock.PathFor(configFile)
+}
+
+// allLocked reports whether every name of pkg has a lock entry.
+func allLocked(lf *lock.File, pkg config.Package) bool {
+	for _, name := range pkg.Name {
+		if _, locked := lf.Get(name); !locked {
+			return false
+		}
+	}
+	return true
+}
+
+// lockPackage resolves the installed version of each name of pkg and records
+// it in lf.
+func lockPackage(ctx context.Context, lf *lock.File, m manager.PackageManager, pkg config.Package) error {
+	model := models.ToModel(pkg)
+	for _, name := range pkg.Name {
+		model.Name = []string{name}
+		res, err := manager.Resolve(ctx, m, model)
+		if err != nil {
+			return err
+		}
+		lf.Set(lock.Entry{
+			Name:       name,
+			Manager:    pkg.Manager,
+			Constraint: pkg.Version,
+			Version:    res.Version,
+			Source:     res.Source,
+			Checksum:   res.Checksum,
+		})
+	}
+	return nil
+}
+
+func init() {
+	lockCmd.Flags().BoolVar(&lockUpdate, "update", false, "Refresh existing entries (all, or the named packages)")
+	rootCmd.AddCommand(lockCmd)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/semver"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// exitOutdated is the exit code of `voltig outdated` when upgrades are available.
+const exitOutdated = 2
+
+var outdatedOutput string
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List config packages with newer versions available",
+	Long: `Show the installed and latest version of every package in voltig.yml that
+has an upgrade available, and whether the latest version still satisfies the
+package's version constraint.
+
+Exits 0 when everything is up to date, 2 when upgrades are available and 1 on
+errors, so it can gate CI jobs.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if outdatedOutput != "text" && outdatedOutput != "json" {
+			logger.Error("Invalid --output, expected text or json", "output", outdatedOutput)
+			os.Exit(1)
+		}
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		m := manager.ForOS()
+		if m == nil {
+			logger.Error("No supported package manager found for this OS")
+			os.Exit(1)
+		}
+		outdater, ok := m.(manager.Outdater)
+		if !ok {
+			logger.Error("Package manager cannot list outdated packages")
+			os.Exit(1)
+		}
+		pkgs, err := profilePackages(cfg, nil)
+		if err != nil {
+			logger.Error("Failed to resolve profile", "error", err)
+			os.Exit(1)
+		}
+		available, err := outdater.Outdated(cmd.Context())
+		if err != nil {
+			logger.Error("Failed to list outdated packages", "error", err)
+			os.Exit(1)
+		}
+		rows := collectOutdated(selectPackages(pkgs, nil).Targets, available)
+		if err := writeOutdated(os.Stdout, rows, outdatedOutput); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
+		}
+		if len(rows) > 0 {
+			os.Exit(exitOutdated)
+		}
+	},
+}
+
+// outdatedRow is one config package with an upgrade available.
+type outdatedRow struct {
+	Name       string `json:"name"`
+	Manager    string `json:"manager"`
+	Current    string `json:"current"`
+	Latest     string `json:"latest"`
+	Constraint string `json:"constraint,omitempty"`
+	// Allowed reports whether Latest satisfies Constraint
+	Allowed bool `json:"allowed"`
+	Pinned  bool `json:"pinned,omitempty"`
+}
+
+// collectOutdated matches the manager's outdated packages against pkgs, in
+// config order. Any of a package's names may match.
+func collectOutdated(pkgs []config.Package, available []models.OutdatedPackage) []outdatedRow {
+	byName := make(map[string]models.OutdatedPackage)
+	for _, o := range available {
+		byName[o.Name] = o
+	}
+	rows := []outdatedRow{}
+	for _, pkg := range pkgs {
+		for _, name := range pkg.Name {
+			o, ok := byName[name]
+			if !ok {
+				continue
+			}
+			row := outdatedRow{Name: name, Manager: pkg.Manager, Current: o.Current, Latest: o.Latest, Allowed: true, Pinned: o.Pinned}
+			if c, err := semver.ParseConstraint(pkg.Version); err == nil && !c.Any() {
+				row.Constraint = c.String()
+				v, err := semver.Parse(o.Latest)
+				row.Allowed = err == nil && c.Check(v)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// writeOutdated writes rows as an aligned table or as JSON.
+func writeOutdated(w io.Writer, rows []outdatedRow, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	if len(rows) == 0 {
+		logger.Info("All packages are up to date")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PACKAGE\tCURRENT\tLATEST\tCONSTRAINT\tALLOWED")
+	for _, r := range rows {
+		constraint, allowed := r.Constraint, "yes"
+		if constraint == "" {
+			constraint = "-"
+		}
+		if !r.Allowed {
+			allowed = "no"
+		}
+		if r.Pinned {
+			allowed += " (pinned)"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.Current, r.Latest, constraint, allowed)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	outdatedCmd.Flags().StringVarP(&outdatedOutput, "output", "o", "text", "Output format: text or json")
+	addProfileFlag(outdatedCmd)
+	rootCmd.AddCommand(outdatedCmd)
+}
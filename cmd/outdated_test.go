@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/models"
+)
+
+func TestCollectOutdated(t *testing.T) {
+	pkgs := []config.Package{
+		{Name: []string{"jq"}, Manager: "brew", Version: "latest"},
+		{Name: []string{"nodejs", "node"}, Manager: "brew", Version: "^20"},
+		{Name: []string{"go"}, Manager: "brew"},
+	}
+	available := []models.OutdatedPackage{
+		{Name: "node", Current: "20.10.0", Latest: "22.1.0"},
+		{Name: "jq", Current: "1.7", Latest: "1.7.1", Pinned: true},
+		{Name: "wget", Current: "1.0", Latest: "1.1"},
+	}
+	rows := collectOutdated(pkgs, available)
+	if len(rows) != 2 || rows[0].Name != "jq" || rows[1].Name != "node" {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if !rows[0].Allowed || rows[0].Constraint != "" || !rows[0].Pinned {
+		t.Errorf("unexpected jq row: %+v", rows[0])
+	}
+	if rows[1].Allowed || rows[1].Constraint != "^20" {
+		t.Errorf("expected node latest to violate ^20: %+v", rows[1])
+	}
+
+	var buf bytes.Buffer
+	if err := writeOutdated(&buf, rows, "text"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PACKAGE") || !strings.Contains(lines[2], "^20") || !strings.HasSuffix(lines[1], "yes (pinned)") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeOutdated(&buf, rows, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []outdatedRow
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 {
+		t.Errorf("invalid JSON output %q: %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	setTestLogger(t)
+	if err := writeOutdated(&buf, collectOutdated(pkgs, nil), "json"); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected empty JSON list, got %q", buf.String())
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/platform"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// profileName holds the --profile flag shared by the package commands.
+var profileName string
+
+// addProfileFlag registers --profile on a package command.
+func addProfileFlag(c *cobra.Command) {
+	c.Flags().StringVar(&profileName, "profile", "", "Limit to packages in the given profile (\""+config.AllProfile+"\" for every package)")
+}
+
+// dryRun holds the --dry-run flag shared by install, update and remove.
+var dryRun bool
+
+// addDryRunFlag registers --dry-run on a package command.
+func addDryRunFlag(c *cobra.Command) {
+	c.Flags().BoolVar(&dryRun, "dry-run", false, "Print the commands that would run without running them")
+}
+
+// printDryRun shows what op would do to pkgs and returns the exit code.
+func printDryRun(ctx context.Context, m manager.PackageManager, op string, pkgs []models.Package, notFound []string) int {
+	if err := manager.DryRun(ctx, os.Stdout, m, op, pkgs); err != nil {
+		logger.Error("Failed to write dry run", "error", err)
+		return 1
+	}
+	logger.Info("Dry run, no changes made", "packages", len(pkgs))
+	if len(notFound) > 0 {
+		logger.Error("Packages not found in config", "packages", notFound)
+		return 1
+	}
+	return 0
+}
+
+// exitCancelled is the exit code when an operation was interrupted or timed
+// out, matching a shell command stopped by SIGINT.
+const exitCancelled = 130
+
+// reportCancelled logs the packages PkgOperation didn't finish because ctx
+// was cancelled, saying whether by a signal or by --timeout.
+func reportCancelled(ctx context.Context, cancelled []string) {
+	if len(cancelled) == 0 {
+		return
+	}
+	reason := "interrupted"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "timed out"
+	}
+	logger.Warn("Cancelled packages ("+reason+")", "packages", cancelled)
+}
+
+// profilePackages returns the packages in scope for this run: those of
+// --profile if given, otherwise of default_profile when no explicit package
+// names were passed, otherwise every package.
+func profilePackages(cfg *config.PackageConfig, args []string) ([]config.Package, error) {
+	name := profileName
+	if name == "" {
+		if len(args) > 0 || cfg.DefaultProfile == "" {
+			return cfg.Packages, nil
+		}
+		name = cfg.DefaultProfile
+	}
+	pkgs, err := cfg.ProfilePackages(name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Using profile", "profile", name, "packages", len(pkgs))
+	return pkgs, nil
+}
+
+// packageSelection is the result of resolving CLI arguments against the config.
+type packageSelection struct {
+	Targets  []config.Package
+	Skipped  []config.Package // not applicable to this platform
+	NotFound []string
+}
+
+// selectPackages resolves args to config packages. With no args every package
+// is selected. Packages whose platforms/when conditions don't match the host
+// are moved to Skipped before any manager is involved.
+func selectPackages(pkgs []config.Package, args []string) packageSelection {
+	var sel packageSelection
+	var candidates []config.Package
+	if len(args) == 0 {
+		candidates = pkgs
+	} else {
+		for _, arg := range args {
+			pkg, found := findPackage(pkgs, arg)
+			if !found {
+				logger.Error("Package not found in config", "package", arg)
+				sel.NotFound = append(sel.NotFound, arg)
+				continue
+			}
+			candidates = append(candidates, pkg)
+		}
+	}
+	for _, pkg := range candidates {
+		if ok, reason := platform.PackageMatches(pkg); !ok {
+			logger.Info("Skipping package", "package", strings.Join(pkg.Name, ", "), "reason", reason)
+			sel.Skipped = append(sel.Skipped, pkg)
+			continue
+		}
+		sel.Targets = append(sel.Targets, pkg)
+	}
+	return sel
+}
+
+// optionalAll is the --with value that includes every optional package.
+const optionalAll = "optional"
+
+/*
+filterOptional drops optional packages when minimal is set, except those
+named in with (or all of them when with contains "optional"). It returns the
+kept packages and the optional ones left out.
+*/
+func filterOptional(pkgs []config.Package, minimal bool, with []string) (kept, skipped []config.Package) {
+	if !minimal {
+		return pkgs, nil
+	}
+	included := make(map[string]bool)
+	for _, w := range with {
+		included[w] = true
+	}
+	for _, pkg := range pkgs {
+		if !pkg.Optional || included[optionalAll] {
+			kept = append(kept, pkg)
+			continue
+		}
+		want := false
+		for _, n := range pkg.Name {
+			want = want || included[n]
+		}
+		if want {
+			kept = append(kept, pkg)
+		} else {
+			skipped = append(skipped, pkg)
+		}
+	}
+	return kept, skipped
+}
+
+// splitOptional partitions failed package names into those of required and
+// optional packages in pkgs.
+func splitOptional(pkgs []config.Package, failed []string) (required, optional []string) {
+	for _, name := range failed {
+		if pkg, ok := findPackage(pkgs, name); ok && pkg.Optional {
+			optional = append(optional, name)
+		} else {
+			required = append(required, name)
+		}
+	}
+	return required, optional
+}
+
+// findPackage returns the config entry that lists name among its names.
+func findPackage(pkgs []config.Package, name string) (config.Package, bool) {
+	for _, pkg := range pkgs {
+		for _, n := range pkg.Name {
+			if n == name {
+				return pkg, true
+			}
+		}
+	}
+	return config.Package{}, false
+}
+
+// packageNames flattens the names of the given packages.
+func packageNames(pkgs []config.Package) []string {
+	var names []string
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name...)
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"voltig/config"
+)
+
+func TestFilterOptional(t *testing.T) {
+	pkgs := []config.Package{
+		{Name: []string{"jq"}},
+		{Name: []string{"docker"}, Optional: true},
+		{Name: []string{"lazygit", "lg"}, Optional: true},
+	}
+	names := func(pkgs []config.Package) string { return strings.Join(packageNames(pkgs), ",") }
+	tests := []struct {
+		minimal       bool
+		with          []string
+		kept, skipped string
+	}{
+		{false, nil, "jq,docker,lazygit,lg", ""},
+		{true, nil, "jq", "docker,lazygit,lg"},
+		{true, []string{"optional"}, "jq,docker,lazygit,lg", ""},
+		{true, []string{"lg"}, "jq,lazygit,lg", "docker"},
+	}
+	for _, tt := range tests {
+		kept, skipped := filterOptional(pkgs, tt.minimal, tt.with)
+		if names(kept) != tt.kept || names(skipped) != tt.skipped {
+			t.Errorf("filterOptional(minimal=%v, with=%v) = %q / %q, want %q / %q", tt.minimal, tt.with, names(kept), names(skipped), tt.kept, tt.skipped)
+		}
+	}
+}
+
+func TestSplitOptional(t *testing.T) {
+	pkgs := []config.Package{
+		{Name: []string{"jq"}},
+		{Name: []string{"docker"}, Optional: true},
+	}
+	required, optional := splitOptional(pkgs, []string{"jq", "docker", "unknown"})
+	if strings.Join(required, ",") != "jq,unknown" || strings.Join(optional, ",") != "docker" {
+		t.Errorf("splitOptional = %v / %v", required, optional)
+	}
+}
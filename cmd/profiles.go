@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"voltig/config"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List package profiles and their package counts",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		names := cfg.ProfileNames()
+		if len(names) == 0 {
+			logger.Info("No profiles defined", "packages", len(cfg.Packages))
+			return
+		}
+		for _, name := range names {
+			pkgs, err := cfg.ProfilePackages(name)
+			if err != nil {
+				logger.Error("Invalid profile", "profile", name, "error", err)
+				continue
+			}
+			profile := cfg.Profiles[name]
+			keyvals := []interface{}{"name", name, "packages", len(pkgs)}
+			if len(profile.Extends) > 0 {
+				keyvals = append(keyvals, "extends", []string(profile.Extends))
+			}
+			if name == cfg.DefaultProfile {
+				keyvals = append(keyvals, "default", true)
+			}
+			if profile.Summary != "" {
+				keyvals = append(keyvals, "summary", profile.Summary)
+			}
+			logger.Info("Profile", keyvals...)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profilesCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"voltig/config"
 	"voltig/internal/manager"
 	"voltig/internal/models"
+	"voltig/internal/state"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
@@ -14,8 +15,15 @@ var removeCmd = &cobra.Command{
 	Use:     "remove [pkg_name]...",
 	Aliases: []string{"rm"},
 	Short:   "Remove one or more packages",
-	Args:    cobra.MinimumNArgs(1),
-	Run: func(_ *cobra.Command, args []string) {
+	Args: func(cmd *cobra.Command, args []string) error {
+		// Removing a whole profile doesn't need package names
+		if profileName != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg, err := config.LoadConfig(configFile)
 		if err != nil {
 			logger.Error("Failed to load config", "error", err)
@@ -26,43 +34,39 @@ var removeCmd = &cobra.Command{
 			logger.Error("No supported package manager found for this OS")
 			os.Exit(1)
 		}
+		pkgs, err := profilePackages(cfg, args)
+		if err != nil {
+			logger.Error("Failed to resolve profile", "error", err)
+			os.Exit(1)
+		}
 
 		var (
-			failedRemovals  []string
-			successRemovals []string
-			notFound        []string
-			targetPkgs      []config.Package
+			failedRemovals    []string
+			successRemovals   []string
+			cancelledRemovals []string
 		)
 
 		// Find packages to remove
-		logger.Info("Removing specified packages", "packages", args)
-		for _, arg := range args {
-			found := false
-			for _, pkg := range cfg.Packages {
-				// Check if the package name is in the list of names
-				for _, name := range pkg.Name {
-					if name == arg {
-						targetPkgs = append(targetPkgs, pkg)
-						found = true
-						break
-					}
-				}
-				if found {
-					break
-				}
-			}
-			if !found {
-				logger.Error("Package not found in config", "package", arg)
-				notFound = append(notFound, arg)
-			}
+		if len(args) == 0 {
+			logger.Info("Removing all packages from profile", "profile", profileName, "count", len(pkgs))
+		} else {
+			logger.Info("Removing specified packages", "packages", args)
 		}
+		sel := selectPackages(pkgs, args)
+		targetPkgs, notFound := sel.Targets, sel.NotFound
 
 		// Remove packages
 		var pkgModels []models.Package
 		for _, pkg := range targetPkgs {
 			pkgModels = append(pkgModels, models.ToModel(pkg))
 		}
-		successRemovals, failedRemovals = manager.PkgOperation("Removing", "removed", pkgModels, m.Remove)
+		if dryRun {
+			os.Exit(printDryRun(ctx, m, manager.OpRemove, pkgModels, notFound))
+		}
+		done := journalOp(ctx, m, manager.OpRemove, pkgModels, 0)
+		successRemovals, failedRemovals, cancelledRemovals = manager.PkgOperation(ctx, "Removing", "removed", pkgModels, withHooks(cfg, manager.OpRemove, m.Remove))
+		done(successRemovals, failedRemovals, cancelledRemovals)
+		forgetOwnership(pkgModels, successRemovals)
 
 		// Print summary
 		if len(successRemovals) > 0 {
@@ -71,12 +75,18 @@ var removeCmd = &cobra.Command{
 		if len(failedRemovals) > 0 {
 			logger.Error("Failed to remove packages", "packages", failedRemovals)
 		}
+		reportCancelled(ctx, cancelledRemovals)
+		if len(sel.Skipped) > 0 {
+			logger.Info("Skipped packages (platform)", "packages", packageNames(sel.Skipped))
+		}
 		if len(notFound) > 0 {
 			logger.Error("Packages not found in config", "packages", notFound)
 		}
 
 		// Exit with error code if any failures
-		if len(failedRemovals) > 0 || len(notFound) > 0 {
+		if len(cancelledRemovals) > 0 {
+			os.Exit(exitCancelled)
+		} else if len(failedRemovals) > 0 || len(notFound) > 0 {
 			os.Exit(1)
 		} else if len(successRemovals) > 0 {
 			logger.Info("All requested packages removed successfully")
@@ -86,6 +96,32 @@ var removeCmd = &cobra.Command{
 	},
 }
 
+// forgetOwnership drops removed packages from the ownership state.
+func forgetOwnership(pkgs []models.Package, removed []string) {
+	if len(removed) == 0 {
+		return
+	}
+	st, err := state.Load(state.DefaultPath())
+	if err != nil {
+		logger.Warn("Failed to load state", "error", err)
+		return
+	}
+	done := make(map[string]bool)
+	for _, name := range removed {
+		done[name] = true
+	}
+	for _, pkg := range pkgs {
+		if done[pkg.Name[0]] {
+			st.Disown(pkg.Manager, pkg.Name[0])
+		}
+	}
+	if err := st.Save(); err != nil {
+		logger.Warn("Failed to save state", "path", st.Path, "error", err)
+	}
+}
+
 func init() {
+	addProfileFlag(removeCmd)
+	addDryRunFlag(removeCmd)
 	rootCmd.AddCommand(removeCmd)
 }
@@ -19,8 +19,8 @@ func (m *removeMockManager) Remove(pkg models.Package, outputFn func(string)) er
 	return nil
 }
 
-func (m *removeMockManager) GetStatus(pkg models.Package) (models.PackageStatus, error) {
-	return models.PackageStatus{}, nil
+func (m *removeMockManager) GetStatus(pkg models.Package) ([]models.PackageStatus, error) {
+	return nil, nil
 }
 
 func (m *removeMockManager) IsAvailable() bool { return true }
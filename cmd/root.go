@@ -1,15 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"os"
-	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
 	"voltig/config"
+	"voltig/internal/manager"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
 )
 
-var configFile string
+var (
+	configFile     string
+	timeout        time.Duration
+	packageTimeout time.Duration
+	// stopTimeout releases the --timeout deadline once the command is done
+	stopTimeout context.CancelFunc = func() {}
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "voltig",
@@ -17,6 +27,14 @@ var rootCmd = &cobra.Command{
 	Long:  `A cross-platform CLI for managing system packages with TUI support.`,
 	// Enable command grouping
 	GroupID: "main",
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		manager.PackageTimeout = packageTimeout
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+			stopTimeout = cancel
+		}
+	},
 }
 
 func init() {
@@ -44,50 +62,9 @@ func init() {
 			}
 		},
 	})
-
-	rootCmd.AddCommand(&cobra.Command{
-		Use:   "lint",
-		Short: "Lint and validate your voltig.yml config file",
-		Run: func(cmd *cobra.Command, args []string) {
-			cfg, err := config.LoadConfig(configFile)
-			if err != nil {
-				logger.Error("Config error", "error", err)
-				os.Exit(1)
-			}
-			// Check for duplicate package names
-			nameSet := make(map[string]struct{})
-			for _, pkg := range cfg.Packages {
-				for _, name := range pkg.Name {
-					if _, exists := nameSet[name]; exists {
-						logger.Error("Duplicate package name", "name", name)
-						os.Exit(1)
-					}
-					nameSet[name] = struct{}{}
-				}
-				if len(pkg.Name) == 0 || pkg.Manager == "" {
-					logger.Error("Missing required fields in package", "package", pkg)
-					os.Exit(1)
-				}
-			}
-			// Check for protected command overrides
-			protected := map[string]struct{}{"install": {}, "update": {}, "remove": {}, "status": {}, "tui": {}, "help": {}, "completion": {}, "lint": {}}
-			for name := range cfg.Commands {
-				if _, found := protected[name]; found {
-					logger.Error("Protected command cannot be overridden", "command", name)
-					os.Exit(1)
-				}
-			}
-			logger.Info("Config validation successful")
-		},
-	})
 }
 
 func Execute() {
-	// List of protected/core commands
-	protected := map[string]struct{}{
-		"install": {}, "update": {}, "remove": {}, "status": {}, "tui": {}, "help": {}, "completion": {}, "lint": {}, "config": {}, "scan": {},
-	}
-
 	// Assign core commands to their group
 	for _, cmd := range rootCmd.Commands() {
 		// Skip help command
@@ -96,46 +73,37 @@ func Execute() {
 		}
 
 		// Assign utility commands
-		if cmd.Name() == "completion" || cmd.Name() == "lint" || cmd.Name() == "config" {
+		if cmd.Name() == "completion" || cmd.Name() == "lint" || cmd.Name() == "config" || cmd.Name() == "profiles" || cmd.Name() == "schema" || cmd.Name() == "init" || cmd.Name() == "doctor" {
 			cmd.GroupID = "utility"
 			continue
 		}
 
 		// Assign all other built-in commands to core group
-		if _, found := protected[cmd.Name()]; found {
+		if _, found := config.ProtectedCommands[cmd.Name()]; found {
 			cmd.GroupID = "core"
 		}
 	}
 	// Load config to register user commands
 	cfg, err := config.LoadConfig(configFile)
 	if err == nil && cfg.Commands != nil {
-		for name, c := range cfg.Commands {
-			if _, found := protected[name]; found {
-				logger.Error("User command not allowed", "command", name, "reason", "name is protected by core CLI")
-				os.Exit(1)
-			}
-			cmdName := name
-			cmdDef := c
-			cmd := &cobra.Command{
-				Use:     cmdName,
-				Short:   "✨ " + cmdDef.Summary,
-				GroupID: "project",
-				Run: func(cmd *cobra.Command, args []string) {
-					logger.Info(cmdDef.Summary)
-					shellCmd := exec.Command("sh", "-c", cmdDef.Command)
-					shellCmd.Stdout = os.Stdout
-					shellCmd.Stderr = os.Stderr
-					shellCmd.Stdin = os.Stdin
-					if err := shellCmd.Run(); err != nil {
-						logger.Error("Command failed", "error", err)
-						os.Exit(1)
-					}
-				},
-			}
-			rootCmd.AddCommand(cmd)
+		entries, err := config.ValidateCommands(cfg.Commands)
+		if err != nil {
+			logger.Error("User command not allowed", "error", err)
+			os.Exit(1)
 		}
+		registerUserCommands(rootCmd, cfg, entries)
 	}
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C and SIGTERM cancel the running command and its subprocesses; a
+	// second Ctrl-C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		logger.Warn("Interrupted, stopping running operations (press Ctrl-C again to force)")
+	}()
+	err = rootCmd.ExecuteContext(ctx)
+	stopTimeout()
+	if err != nil {
 		logger.Error("Command execution failed", "error", err)
 		os.Exit(1)
 	}
@@ -143,6 +111,8 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "voltig.yml", "Path to YAML config file")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Cancel the whole command after this long, e.g. 30m (0 for no limit)")
+	rootCmd.PersistentFlags().DurationVar(&packageTimeout, "package-timeout", 0, "Cancel each package operation after this long, unless the package sets timeout (0 for no limit)")
 
 	// Set up command groups
 	rootCmd.AddGroup(&cobra.Group{
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"runtime"
 	"strings"
 	"voltig/internal/models"
+	"voltig/internal/proc"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
@@ -14,8 +16,8 @@ import (
 var scanCmd = &cobra.Command{
 	Use:   "scan",
 	Short: "Scan system for all installed packages and versions",
-	RunE: func(_cmd *cobra.Command, _args []string) error {
-		pkgs, err := scanSystemPackages()
+	RunE: func(cmd *cobra.Command, _args []string) error {
+		_, pkgs, err := scanSystemPackages(cmd.Context())
 		if err != nil {
 			return err
 		}
@@ -30,47 +32,93 @@ func init() {
 	rootCmd.AddCommand(scanCmd)
 }
 
-// scanSystemPackages detects the OS/manager and returns all system packages
-func scanSystemPackages() ([]models.PackageStatus, error) {
+// scanSystemPackages detects the OS/manager and returns its name and all system
+// packages. Cancelling ctx stops the scan.
+func scanSystemPackages(ctx context.Context) (string, []models.PackageStatus, error) {
 	osType := runtime.GOOS
 	var cmd *exec.Cmd
 	var parser func(string) []models.PackageStatus
+	var managerName string
 
 	switch osType {
 	case "darwin":
-		cmd = exec.Command("brew", "list", "--versions")
+		cmd = proc.Command(ctx, "brew", "list", "--versions")
 		parser = parseBrew
+		managerName = "brew"
 	case "linux":
 		// Try apt, fallback to pacman
 		if _, err := exec.LookPath("dpkg-query"); err == nil {
-			cmd = exec.Command("dpkg-query", "-W", "-f=${binary:Package}\t${Version}\n")
+			cmd = proc.Command(ctx, "dpkg-query", "-W", "-f=${binary:Package}\t${Version}\n")
 			parser = parseDpkg
+			managerName = "apt"
 		} else if _, err := exec.LookPath("pacman"); err == nil {
-			cmd = exec.Command("pacman", "-Q")
+			cmd = proc.Command(ctx, "pacman", "-Q")
 			parser = parsePacman
+			managerName = "pacman"
 		} else {
-			return nil, fmt.Errorf("no supported package manager found for this OS")
+			return "", nil, fmt.Errorf("no supported package manager found for this OS")
 		}
 	case "windows":
 		if _, err := exec.LookPath("choco"); err == nil {
-			cmd = exec.Command("choco", "list", "--local-only")
+			cmd = proc.Command(ctx, "choco", "list", "--local-only")
 			parser = parseChoco
+			managerName = "choco"
 		} else if _, err := exec.LookPath("winget"); err == nil {
-			cmd = exec.Command("winget", "list")
+			cmd = proc.Command(ctx, "winget", "list")
 			parser = parseWinget
+			managerName = "winget"
 		} else {
-			return nil, fmt.Errorf("no supported package manager found for this OS")
+			return "", nil, fmt.Errorf("no supported package manager found for this OS")
 		}
 	default:
 		logger.Error("Unsupported OS", "os", osType)
-		return nil, fmt.Errorf("unsupported OS: %s", osType)
+		return "", nil, fmt.Errorf("unsupported OS: %s", osType)
 	}
 	out, err := cmd.Output()
 	if err != nil {
 		logger.Error("Error detecting packages", "error", err)
+		return "", nil, err
+	}
+	return managerName, parser(string(out)), nil
+}
+
+// dependencyCommands print, per scanned manager, the packages installed only
+// as dependencies of others, one name per line.
+var dependencyCommands = map[string][]string{
+	"brew":   {"brew", "list", "--installed-as-dependency"},
+	"apt":    {"apt-mark", "showauto"},
+	"pacman": {"pacman", "-Qqd"},
+}
+
+// scanDependencies returns the packages managerName installed only as
+// dependencies. Managers that don't track this report none.
+func scanDependencies(ctx context.Context, managerName string) (map[string]bool, error) {
+	args, ok := dependencyCommands[managerName]
+	if !ok {
+		return nil, nil
+	}
+	out, err := proc.Command(ctx, args[0], args[1:]...).Output()
+	if err != nil {
 		return nil, err
 	}
-	return parser(string(out)), nil
+	deps := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			deps[name] = true
+		}
+	}
+	return deps, nil
+}
+
+// withoutDependencies returns the scanned packages not in deps.
+func withoutDependencies(scanned []models.PackageStatus, deps map[string]bool) []models.PackageStatus {
+	var top []models.PackageStatus
+	for _, p := range scanned {
+		if !deps[p.Name] {
+			top = append(top, p)
+		}
+	}
+	return top
 }
 
 func parseBrew(out string) []models.PackageStatus {
@@ -79,7 +127,7 @@ func parseBrew(out string) []models.PackageStatus {
 	for _, l := range lines {
 		fields := strings.Fields(l)
 		if len(fields) >= 2 {
-			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: fields[1], Status: "installed"})
+			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: models.ParseVersion(fields[1], "brew"), Status: "installed"})
 		}
 	}
 	return pkgs
@@ -91,7 +139,7 @@ func parseDpkg(out string) []models.PackageStatus {
 	for _, l := range lines {
 		fields := strings.Split(l, "\t")
 		if len(fields) >= 2 {
-			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: fields[1], Status: "installed"})
+			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: models.ParseVersion(fields[1], "apt"), Status: "installed"})
 		}
 	}
 	return pkgs
@@ -103,7 +151,7 @@ func parsePacman(out string) []models.PackageStatus {
 	for _, l := range lines {
 		fields := strings.Fields(l)
 		if len(fields) >= 2 {
-			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: fields[1], Status: "installed"})
+			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: models.ParseVersion(fields[1], "pacman"), Status: "installed"})
 		}
 	}
 	return pkgs
@@ -115,7 +163,7 @@ func parseChoco(out string) []models.PackageStatus {
 	for _, l := range lines {
 		fields := strings.Fields(l)
 		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "Chocolatey") {
-			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: fields[1], Status: "installed"})
+			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: models.ParseVersion(fields[1], "choco"), Status: "installed"})
 		}
 	}
 	return pkgs
@@ -127,7 +175,7 @@ func parseWinget(out string) []models.PackageStatus {
 	for _, l := range lines {
 		fields := strings.Fields(l)
 		if len(fields) >= 3 && fields[0] != "Name" {
-			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: fields[2], Status: "installed"})
+			pkgs = append(pkgs, models.PackageStatus{Name: fields[0], Version: models.ParseVersion(fields[2], "winget"), Status: "installed"})
 		}
 	}
 	return pkgs
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"voltig/config"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for voltig.yml",
+	Long: `Print the JSON Schema for voltig.yml so editors can validate and autocomplete it.
+
+For the YAML language server, save it and reference it at the top of voltig.yml:
+
+  voltig schema > .voltig.schema.json
+  # yaml-language-server: $schema=./.voltig.schema.json`,
+	Args: cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.JSONSchema()); err != nil {
+			logger.Error("Failed to write schema", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/snapshot"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotForce  bool
+	snapshotOutput string
+	snapshotYes    bool
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save, compare and restore the installed toolchain",
+	Long: `Snapshots record every package the system package manager reports, plus the
+status and version of each voltig.yml package, e.g. before an OS upgrade.
+They are saved in $XDG_STATE_HOME/voltig/snapshots (default ~/.local/state);
+a name containing a '/' or ending in .json is used as a file path instead.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		_ = cmd.Help()
+	},
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Save the installed packages and versions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := snapshot.PathFor(args[0])
+		if err != nil {
+			logger.Error("Invalid snapshot", "error", err)
+			os.Exit(1)
+		}
+		// The config is optional; without it only the system scan is saved
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Warn("No config loaded; saving system packages only", "error", err)
+			cfg = nil
+		}
+		snap := createSnapshot(cmd.Context(), args[0], cfg, manager.ForOS(), scanSystemPackages)
+		if err := snap.Save(path, snapshotForce); err != nil {
+			logger.Error("Failed to save snapshot", "error", err, "hint", "use --force to overwrite")
+			os.Exit(1)
+		}
+		logger.Info("Saved snapshot", "name", args[0], "path", displayPath(path), "packages", len(snap.Packages))
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare two snapshots",
+	Args:  cobra.ExactArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		if snapshotOutput != "text" && snapshotOutput != "json" {
+			logger.Error("Invalid --output, expected text or json", "output", snapshotOutput)
+			os.Exit(1)
+		}
+		a, err := snapshot.Load(args[0])
+		if err != nil {
+			logger.Error("Failed to load snapshot", "error", err)
+			os.Exit(1)
+		}
+		b, err := snapshot.Load(args[1])
+		if err != nil {
+			logger.Error("Failed to load snapshot", "error", err)
+			os.Exit(1)
+		}
+		if err := writeSnapshotDiff(os.Stdout, snapshot.Diff(a, b), snapshotOutput); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Install the package versions recorded in a snapshot",
+	Long: `Install every package in the snapshot that is missing or at another version,
+at the recorded version, through the package manager that installed it.
+Packages installed since the snapshot are left alone. Packages of managers
+voltig doesn't support here, or whose recorded version can no longer be
+installed, are reported.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		snap, err := snapshot.Load(args[0])
+		if err != nil {
+			logger.Error("Failed to load snapshot", "error", err)
+			os.Exit(1)
+		}
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, assumeYes: snapshotYes}
+		if code := restoreSnapshot(cmd.Context(), snap, manager.ForName, p, os.Stdout); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+/*
+createSnapshot records the packages scan reports and, when cfg and m are
+given, the installed config packages with their status and constraint.
+Config packages that aren't installed are left out. A failed scan is logged
+and the snapshot holds the config packages only.
+*/
+func createSnapshot(ctx context.Context, name string, cfg *config.PackageConfig, m manager.PackageManager, scan func(context.Context) (string, []models.PackageStatus, error)) *snapshot.Snapshot {
+	snap := &snapshot.Snapshot{Name: name, CreatedAt: time.Now().UTC(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	snap.Host, _ = os.Hostname()
+	managerName, found, err := scan(ctx)
+	if err != nil {
+		logger.Warn("Failed to scan installed packages", "error", err)
+	}
+	for _, p := range found {
+		snap.Add(snapshot.Package{Name: p.Name, Manager: managerName, Version: p.Version.Raw})
+	}
+	if cfg == nil || m == nil {
+		return snap
+	}
+	snap.Config = cfg.Path
+	for _, pkg := range selectPackages(cfg.Packages, nil).Targets {
+		statuses, err := m.GetStatus(ctx, models.ToModel(pkg))
+		if err != nil {
+			continue
+		}
+		for _, status := range statuses {
+			if !strings.HasPrefix(status.Status, "installed") {
+				continue
+			}
+			snap.Add(snapshot.Package{Name: status.Name, Manager: pkg.Manager, Version: installedVersion(status), Status: status.Status, Constraint: pkg.Version, Config: true})
+		}
+	}
+	return snap
+}
+
+// writeSnapshotDiff writes changes sync-style (+ added, ~ changed, - removed)
+// or as JSON.
+func writeSnapshotDiff(w io.Writer, changes []snapshot.Change, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+	if len(changes) == 0 {
+		logger.Info("Snapshots are identical")
+		return nil
+	}
+	for _, c := range changes {
+		var line string
+		switch c.Kind {
+		case snapshot.Added:
+			line = SuccessStyle.Render(fmt.Sprintf("+ %s %s", c.Name, c.To))
+		case snapshot.Changed:
+			line = WarningStyle.Render(fmt.Sprintf("~ %s %s -> %s", c.Name, c.From, c.To))
+		case snapshot.Removed:
+			line = ErrorStyle.Render(fmt.Sprintf("- %s %s", c.Name, c.From))
+		}
+		if _, err := fmt.Fprintf(w, "  %s (%s)\n", line, c.Manager); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+restoreSnapshot installs, at the recorded version, the snapshot packages that
+are missing or at another version, using managers to find the manager of each
+package. Packages installed outside their manager (e.g. node from nvm) are
+skipped. It returns the exit code.
+*/
+func restoreSnapshot(ctx context.Context, snap *snapshot.Snapshot, managers func(string) manager.PackageManager, p *prompter, out io.Writer) int {
+	byManager := make(map[string][]models.Package)
+	var unsupported, unresolved, external []string
+	for _, entry := range snap.Packages {
+		if entry.Status != "" && entry.Status != "installed" {
+			external = append(external, entry.Name)
+			continue
+		}
+		m := managers(entry.Manager)
+		if m == nil {
+			unsupported = append(unsupported, entry.Manager+":"+entry.Name)
+			continue
+		}
+		// Recorded versions are installed ones, e.g. brew's 1.7.1_1, so drop
+		// revisions before checking and let LockedVersion find the formula
+		pkg := models.Package{Name: []string{entry.Name}, Manager: entry.Manager, Version: models.ParseVersion(entry.Version, "").String()}
+		if _, satisfied, _ := manager.Satisfied(ctx, m, pkg); satisfied {
+			continue
+		}
+		picked, err := manager.LockedVersion(ctx, m, pkg)
+		if err != nil {
+			logger.Error("Cannot install recorded version", "package", entry.Name, "version", entry.Version, "error", err)
+			unresolved = append(unresolved, entry.Name)
+			continue
+		}
+		byManager[entry.Manager] = append(byManager[entry.Manager], picked)
+	}
+	if len(external) > 0 {
+		logger.Info("Skipped packages installed outside their manager", "packages", external)
+	}
+	if len(unsupported) > 0 {
+		logger.Warn("Skipped packages of unsupported managers", "packages", unsupported)
+	}
+
+	managerNames := sortedKeys(byManager)
+	if len(managerNames) == 0 {
+		if len(unresolved) > 0 {
+			return 1
+		}
+		logger.Info("Everything in the snapshot is installed")
+		return 0
+	}
+	_, _ = fmt.Fprintln(out, HeaderStyle.Render("Restore "+snap.Name+" ("+snap.CreatedAt.Local().Format("2006-01-02 15:04")+"):"))
+	for _, name := range managerNames {
+		for _, pkg := range byManager[name] {
+			_, _ = fmt.Fprintf(out, "  %s (%s)\n", SuccessStyle.Render(fmt.Sprintf("+ install %s %s", pkg.Name[0], pkg.Version)), name)
+		}
+	}
+	if dryRun {
+		logger.Info("Dry run, no changes made")
+		return 0
+	}
+	if !p.confirm("Install these packages?", true) {
+		logger.Info("Restore cancelled")
+		return 1
+	}
+
+	var installed, failed, cancelled []string
+	for _, name := range managerNames {
+		m, pkgs := managers(name), byManager[name]
+		done := journalOp(ctx, m, manager.OpInstall, pkgs, 0)
+		s, f, c := manager.PkgOperation(ctx, "Restoring", "restored", pkgs, m.Install)
+		done(s, f, c)
+		installed, failed, cancelled = append(installed, s...), append(failed, f...), append(cancelled, c...)
+	}
+	failed = append(failed, unresolved...)
+	sort.Strings(failed)
+	if len(installed) > 0 {
+		logger.Info("Restored packages", "packages", installed)
+	}
+	if len(failed) > 0 {
+		logger.Error("Failed to restore packages", "packages", failed)
+	}
+	reportCancelled(ctx, cancelled)
+	switch {
+	case len(cancelled) > 0:
+		return exitCancelled
+	case len(failed) > 0:
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	snapshotCreateCmd.Flags().BoolVar(&snapshotForce, "force", false, "Overwrite an existing snapshot")
+	snapshotDiffCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "text", "Output format: text or json")
+	snapshotRestoreCmd.Flags().BoolVarP(&snapshotYes, "yes", "y", false, "Install without prompting")
+	addDryRunFlag(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotDiffCmd, snapshotRestoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
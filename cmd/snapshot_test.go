@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/snapshot"
+)
+
+func TestCreateSnapshot(t *testing.T) {
+	setTestLogger(t)
+	cfg := &config.PackageConfig{Packages: []config.Package{
+		{Name: []string{"node"}, Manager: "brew", Version: "^20"},
+		{Name: []string{"rg"}, Manager: "brew"},
+	}}
+	m := &fakeManager{installed: map[string]string{"node": "20.11.1"}}
+	scan := func(context.Context) (string, []models.PackageStatus, error) {
+		return "brew", []models.PackageStatus{{Name: "jq", Version: models.ParseVersion("1.7.1", "brew")}, {Name: "node", Version: models.ParseVersion("20.11.1", "brew")}}, nil
+	}
+	snap := createSnapshot(context.Background(), "test", cfg, m, scan)
+	var got []string
+	for _, p := range snap.Packages {
+		got = append(got, p.Name+"@"+p.Version)
+	}
+	if strings.Join(got, ",") != "jq@1.7.1,node@20.11.1" {
+		t.Errorf("unexpected packages %v (missing rg must be left out)", got)
+	}
+	if node := snap.Packages[1]; !node.Config || node.Constraint != "^20" || node.Status != "installed" {
+		t.Errorf("expected node to carry its config status, got %+v", node)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	setTestLogger(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Cleanup(func() { dryRun = false })
+	m := &fakeManager{installed: map[string]string{"jq": "1.7.1"}}
+	managers := func(name string) manager.PackageManager {
+		if name == "brew" {
+			return m
+		}
+		return nil
+	}
+	snap := &snapshot.Snapshot{Name: "test", Packages: []snapshot.Package{
+		{Name: "jq", Manager: "brew", Version: "1.7.1"},
+		{Name: "go", Manager: "brew", Version: "1.22.1"},
+		{Name: "node", Manager: "brew", Version: "20.11.1", Status: "installed (nvm)", Config: true},
+		{Name: "curl", Manager: "apt", Version: "8.5.0"},
+	}}
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+
+	dryRun = true
+	if code := restoreSnapshot(context.Background(), snap, managers, p, &out); code != 0 || len(m.ops) != 0 {
+		t.Fatalf("dry run: code=%d ops=%v", code, m.ops)
+	}
+	if !strings.Contains(out.String(), "+ install go 1.22.1") {
+		t.Errorf("expected plan output, got:\n%s", out.String())
+	}
+
+	dryRun = false
+	if code := restoreSnapshot(context.Background(), snap, managers, p, &out); code != 0 {
+		t.Fatalf("restore failed with code %d", code)
+	}
+	if strings.Join(m.ops, ",") != "install go" {
+		t.Errorf("expected only go to be installed, got %v", m.ops)
+	}
+}
+
+func TestSnapshotRoundTrip_Revision(t *testing.T) {
+	setTestLogger(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := &listingFake{fakeManager: &fakeManager{installed: map[string]string{"jq": "1.7.1_1", "node": "20.11.1_2"}}, versions: map[string][]string{
+		"jq":   {"1.7.1"},
+		"node": {"22.3.0", "20"},
+	}}
+	scan := func(context.Context) (string, []models.PackageStatus, error) {
+		return "brew", []models.PackageStatus{{Name: "jq", Version: models.ParseVersion("1.7.1_1", "brew")}, {Name: "node", Version: models.ParseVersion("20.11.1_2", "brew")}}, nil
+	}
+	snap := createSnapshot(context.Background(), "test", nil, nil, scan)
+	path := filepath.Join(t.TempDir(), "test.json")
+	if err := snap.Save(path, false); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := snapshot.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	managers := func(string) manager.PackageManager { return m }
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := restoreSnapshot(context.Background(), loaded, managers, p, &out); code != 0 || len(m.ops) != 0 {
+		t.Fatalf("restoring onto the same machine: code=%d ops=%v", code, m.ops)
+	}
+
+	delete(m.installed, "jq")
+	delete(m.installed, "node")
+	if code := restoreSnapshot(context.Background(), loaded, managers, p, &out); code != 0 {
+		t.Fatalf("restore failed with code %d", code)
+	}
+	if strings.Join(m.ops, ",") != "install jq,install node" {
+		t.Errorf("unexpected operations: %v", m.ops)
+	}
+}
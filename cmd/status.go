@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
 	"voltig/config"
 	"voltig/internal/manager"
 	"voltig/internal/models"
+	"voltig/internal/platform"
+	"voltig/internal/semver"
 	"voltig/pkg/logger"
 
 	"github.com/spf13/cobra"
@@ -15,7 +22,7 @@ var statusCmd = &cobra.Command{
 	Aliases: []string{"st"},
 	Short:   "Show installation status of all packages",
 	Args:    cobra.NoArgs,
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
 		cfg, err := config.LoadConfig(configFile)
 		if err != nil {
 			logger.Error("Failed to load config", "error", err)
@@ -26,13 +33,87 @@ var statusCmd = &cobra.Command{
 			logger.Error("No supported package manager found for this OS")
 			os.Exit(1)
 		}
-		for _, pkg := range cfg.Packages {
-			status, _ := m.GetStatus(models.ToModel(pkg))
-			logger.Info("Package status", "name", status.Name, "status", status.Status, "version", status.Version)
+		pkgs, err := profilePackages(cfg, nil)
+		if err != nil {
+			logger.Error("Failed to resolve profile", "error", err)
+			os.Exit(1)
+		}
+		if err := writeStatus(os.Stdout, statusRows(cmd.Context(), m, pkgs)); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
 		}
 	},
 }
 
+// statusRow is one name of a config package in the status table.
+type statusRow struct {
+	Name       string
+	Status     string
+	Version    models.Version
+	Constraint string
+	Check      string // constraint check, or why the package was skipped
+}
+
+// statusRows returns a row per name of pkgs, in config order. Names whose
+// status can't be read are shown as unknown.
+func statusRows(ctx context.Context, m manager.PackageManager, pkgs []config.Package) []statusRow {
+	var rows []statusRow
+	for _, pkg := range pkgs {
+		if ok, reason := platform.PackageMatches(pkg); !ok {
+			for _, name := range pkg.Name {
+				rows = append(rows, statusRow{Name: name, Status: "skipped (platform)", Constraint: pkg.Version, Check: reason})
+			}
+			continue
+		}
+		statuses, err := m.GetStatus(ctx, models.ToModel(pkg))
+		if err != nil {
+			logger.Warn("Failed to get package status", "package", strings.Join(pkg.Name, ", "), "error", err)
+			statuses = nil
+			for _, name := range pkg.Name {
+				statuses = append(statuses, models.PackageStatus{Name: name, Status: "unknown"})
+			}
+		}
+		for _, status := range statuses {
+			row := statusRow{Name: status.Name, Status: status.Status, Version: status.Version, Constraint: pkg.Version, Check: constraintCheck(pkg, status)}
+			if pkg.Optional {
+				row.Status += " (optional)"
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// writeStatus writes rows as an aligned table.
+func writeStatus(w io.Writer, rows []statusRow) error {
+	if len(rows) == 0 {
+		logger.Info("No packages in config")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PACKAGE\tSTATUS\tVERSION\tSOURCE\tCONSTRAINT\tCHECK")
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.Status, orDash(r.Version.String()), orDash(r.Version.Source),
+			orDash(r.Constraint), orDash(r.Check))
+	}
+	return tw.Flush()
+}
+
+// constraintCheck compares an installed package against its version
+// constraint, returning ok, outdated or unsatisfied, or "" when the package is
+// missing or has no constraint.
+func constraintCheck(pkg config.Package, status models.PackageStatus) string {
+	if !strings.HasPrefix(status.Status, "installed") {
+		return ""
+	}
+	c, err := semver.ParseConstraint(pkg.Version)
+	if err != nil || c.Any() {
+		return ""
+	}
+	return semver.Status(c, status.Version.String())
+}
+
 func init() {
+	addProfileFlag(statusCmd)
 	rootCmd.AddCommand(statusCmd)
 }
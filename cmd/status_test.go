@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/models"
+)
+
+// failingStatus is a fakeManager whose GetStatus fails for fail.
+type failingStatus struct {
+	*fakeManager
+	fail string
+}
+
+func (m *failingStatus) GetStatus(ctx context.Context, pkg models.Package) ([]models.PackageStatus, error) {
+	if pkg.Name[0] == m.fail {
+		return nil, errors.New("status unavailable")
+	}
+	return m.fakeManager.GetStatus(ctx, pkg)
+}
+
+func TestStatusRows(t *testing.T) {
+	setTestLogger(t)
+	pkgs := []config.Package{
+		{Name: []string{"node", "rust"}, Manager: "brew", Version: "^20"},
+		{Name: []string{"jq"}, Manager: "brew", Optional: true},
+		{Name: []string{"winget-only"}, Manager: "winget", Platforms: []string{"plan9"}},
+	}
+	m := &fakeManager{installed: map[string]string{"node": "v20.11", "jq": "1.7.1_1"}}
+
+	var buf bytes.Buffer
+	if err := writeStatus(&buf, statusRows(context.Background(), m, pkgs)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	want := []string{
+		"PACKAGE      STATUS                VERSION  SOURCE  CONSTRAINT  CHECK",
+		"node         installed             20.11.0  fake    ^20         ok",
+		"rust         missing               -        -       ^20         -",
+		"jq           installed (optional)  1.7.1    fake    -           -",
+		"winget-only  skipped (platform)    -        -       -           platform",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i]) {
+			t.Errorf("line %d = %q, want prefix %q", i, line, want[i])
+		}
+	}
+}
+
+func TestStatusRows_Unknown(t *testing.T) {
+	setTestLogger(t)
+	pkgs := []config.Package{
+		{Name: []string{"node", "npm"}, Manager: "brew"},
+		{Name: []string{"jq"}, Manager: "brew"},
+	}
+	m := &failingStatus{fakeManager: &fakeManager{installed: map[string]string{"jq": "1.7.1"}}, fail: "node"}
+
+	rows := statusRows(context.Background(), m, pkgs)
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.Name+" "+r.Status)
+	}
+	if want := "node unknown, npm unknown, jq installed"; strings.Join(got, ", ") != want {
+		t.Errorf("got rows %q, want %q", strings.Join(got, ", "), want)
+	}
+}
@@ -14,4 +14,8 @@ var (
 	ErrorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FF005F")).
 			Bold(true)
+
+	WarningStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFAF00")).
+			Bold(true)
 )
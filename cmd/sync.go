@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"voltig/config"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/internal/semver"
+	"voltig/internal/state"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDryRun bool
+	syncYes    bool
+	syncPrune  bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile installed packages with voltig.yml",
+	Long: `Install missing packages, upgrade packages that violate their declared
+version and, with --prune, remove packages voltig installed from this config
+that are no longer listed. The plan is printed first and applied after
+confirmation (or immediately with --yes).
+
+Only packages recorded as installed by voltig are ever pruned; ownership is
+kept in $XDG_STATE_HOME/voltig/state.json (default ~/.local/state).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+		m := manager.ForOS()
+		if m == nil {
+			logger.Error("No supported package manager found for this OS")
+			os.Exit(1)
+		}
+		st, err := state.Load(state.DefaultPath())
+		if err != nil {
+			logger.Error("Failed to load state", "error", err)
+			os.Exit(1)
+		}
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, assumeYes: syncYes}
+		if code := runSync(cmd.Context(), cfg, m, st, p, os.Stdout); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+// Sync action kinds.
+const (
+	actionInstall = "install"
+	actionUpgrade = "upgrade"
+	actionRemove  = "remove"
+)
+
+// syncAction is one step of a sync plan.
+type syncAction struct {
+	Kind   string
+	Pkg    models.Package
+	Reason string
+	// Missing is set on installs of packages none of whose names were
+	// installed; only those are recorded as installed by voltig.
+	Missing bool
+}
+
+/*
+planSync compares the packages in scope with the machine: missing packages are
+installed, outdated ones upgraded, and ones that don't satisfy their
+constraint reinstalled at a satisfying version. With prune, the names of
+packages st records as installed from this config that it no longer lists are
+removed.
+*/
+func planSync(ctx context.Context, cfg *config.PackageConfig, m manager.PackageManager, st *state.State, prune bool) ([]syncAction, error) {
+	pkgs, err := profilePackages(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	var plan []syncAction
+	for _, pkg := range selectPackages(pkgs, nil).Targets {
+		model := models.ToModel(pkg)
+		statuses, err := m.GetStatus(ctx, model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of %s: %w", pkg.Name[0], err)
+		}
+		missing := len(statuses) > 0
+		for _, status := range statuses {
+			missing = missing && status.Status == "missing"
+		}
+		// The first name needing a change decides the action for the package
+		for _, status := range statuses {
+			action := syncAction{Pkg: model}
+			switch {
+			case status.Status == "missing":
+				action.Kind, action.Reason, action.Missing = actionInstall, "missing", missing
+			case constraintCheck(pkg, status) == semver.StatusOutdated:
+				action.Kind, action.Reason = actionUpgrade, fmt.Sprintf("%s does not satisfy %s", status.Version, pkg.Version)
+			case constraintCheck(pkg, status) == semver.StatusUnsatisfied:
+				action.Kind, action.Reason = actionInstall, fmt.Sprintf("%s does not satisfy %s", status.Version, pkg.Version)
+			default:
+				continue
+			}
+			if len(statuses) > 1 {
+				action.Reason = status.Name + " " + action.Reason
+			}
+			plan = append(plan, action)
+			break
+		}
+	}
+	if prune {
+		for _, owned := range st.OwnedBy(cfg.Path) {
+			// Names moved to or kept in another entry are still declared
+			var unlisted []string
+			for _, name := range owned.Names {
+				if _, listed := findPackage(cfg.Packages, name); !listed {
+					unlisted = append(unlisted, name)
+				}
+			}
+			if len(unlisted) == 0 {
+				continue
+			}
+			pkg := models.Package{Name: unlisted, Manager: owned.Manager, Version: owned.Version}
+			plan = append(plan, syncAction{Kind: actionRemove, Pkg: pkg, Reason: "no longer in " + displayPath(cfg.Path)})
+		}
+	}
+	return plan, nil
+}
+
+// printPlan writes the plan terraform-style: + install, ~ upgrade, - remove,
+// each followed by the hooks it runs.
+func printPlan(w io.Writer, plan []syncAction) {
+	for _, a := range plan {
+		var line, op string
+		name := strings.Join(a.Pkg.Name, ", ")
+		switch a.Kind {
+		case actionInstall:
+			line, op = SuccessStyle.Render(fmt.Sprintf("+ install %s", name)), manager.OpInstall
+		case actionUpgrade:
+			line = WarningStyle.Render(fmt.Sprintf("~ upgrade %s", name))
+		case actionRemove:
+			line, op = ErrorStyle.Render(fmt.Sprintf("- remove  %s", name)), manager.OpRemove
+		}
+		_, _ = fmt.Fprintf(w, "  %s (%s)\n", line, a.Reason)
+		for _, stage := range []string{"pre_" + op, "post_" + op} {
+			for _, hook := range a.Pkg.Hooks[stage] {
+				_, _ = fmt.Fprintf(w, "      %s: %s\n", stage, hook)
+			}
+		}
+	}
+}
+
+// runSync plans and, unless --dry-run, applies a sync. It returns the exit code.
+func runSync(ctx context.Context, cfg *config.PackageConfig, m manager.PackageManager, st *state.State, p *prompter, out io.Writer) int {
+	plan, err := planSync(ctx, cfg, m, st, syncPrune)
+	if err != nil {
+		logger.Error("Failed to plan sync", "error", err)
+		return 1
+	}
+	if len(plan) == 0 {
+		logger.Info("Everything is in sync")
+		return 0
+	}
+	_, _ = fmt.Fprintln(out, HeaderStyle.Render("Voltig sync plan:"))
+	printPlan(out, plan)
+	if syncDryRun {
+		logger.Info("Dry run, no changes made", "actions", len(plan))
+		return 0
+	}
+	removals := false
+	for _, a := range plan {
+		removals = removals || a.Kind == actionRemove
+	}
+	// --yes applies the plan; prompting defaults to no when packages would be removed
+	if !p.assumeYes && !p.confirm("Apply these changes?", !removals) {
+		logger.Info("Sync cancelled")
+		return 1
+	}
+
+	var installs, upgrades, removes []models.Package
+	// Only packages missing beforehand count as installed by voltig
+	missing := make(map[string]bool)
+	for _, a := range plan {
+		switch a.Kind {
+		case actionInstall:
+			pkg, err := manager.PickVersion(ctx, m, a.Pkg)
+			if err != nil {
+				logger.Error("Failed to resolve version", "package", a.Pkg.Name[0], "error", err)
+				return 1
+			}
+			installs = append(installs, pkg)
+			missing[pkg.Name[0]] = a.Missing
+		case actionUpgrade:
+			// A plain upgrade can overshoot the constraint, e.g. node 22 for
+			// ^20; install the versioned release instead when latest won't do
+			pkg, err := manager.PickVersion(ctx, m, a.Pkg)
+			if err != nil {
+				logger.Error("Failed to resolve version", "package", a.Pkg.Name[0], "error", err)
+				return 1
+			}
+			if pkg.Version == "latest" {
+				upgrades = append(upgrades, pkg)
+			} else {
+				installs = append(installs, pkg)
+			}
+		case actionRemove:
+			removes = append(removes, a.Pkg)
+		}
+	}
+	var failed, cancelled []string
+	done := journalOp(ctx, m, manager.OpInstall, installs, 0)
+	installed, f, c := manager.PkgOperation(ctx, "Installing", "installed", installs, withHooks(cfg, manager.OpInstall, m.Install))
+	done(installed, f, c)
+	failed, cancelled = append(failed, f...), append(cancelled, c...)
+	done = journalOp(ctx, m, manager.OpUpdate, upgrades, 0)
+	upgraded, f, c := manager.PkgOperation(ctx, "Upgrading", "upgraded", upgrades, func(ctx context.Context, pkg models.Package, _ func(string)) error {
+		return m.Update(ctx, pkg)
+	})
+	done(upgraded, f, c)
+	failed, cancelled = append(failed, f...), append(cancelled, c...)
+	done = journalOp(ctx, m, manager.OpRemove, removes, 0)
+	removed, f, c := manager.PkgOperation(ctx, "Removing", "removed", removes, withHooks(cfg, manager.OpRemove, m.Remove))
+	done(removed, f, c)
+	failed, cancelled = append(failed, f...), append(cancelled, c...)
+
+	succeeded := make(map[string]bool)
+	for _, name := range append(append(installed, upgraded...), removed...) {
+		succeeded[name] = true
+	}
+	for _, pkg := range installs {
+		if succeeded[pkg.Name[0]] && missing[pkg.Name[0]] {
+			st.Own(pkg.Manager, pkg.Name, pkg.Version, cfg.Path)
+		}
+	}
+	for _, pkg := range removes {
+		for _, name := range pkg.Name {
+			if succeeded[name] {
+				st.DisownName(pkg.Manager, name)
+			}
+		}
+	}
+	if err := st.Save(); err != nil {
+		logger.Warn("Failed to save state", "path", st.Path, "error", err)
+	}
+
+	failed, failedOptional := splitOptional(cfg.Packages, failed)
+	if len(failedOptional) > 0 {
+		logger.Warn("Failed optional packages", "packages", failedOptional)
+	}
+	if len(failed) > 0 {
+		logger.Error("Sync finished with failures", "packages", failed)
+	}
+	reportCancelled(ctx, cancelled)
+	if len(cancelled) > 0 {
+		return exitCancelled
+	}
+	if len(failed) > 0 {
+		return 1
+	}
+	logger.Info("Sync complete", "installed", len(installed), "upgraded", len(upgraded), "removed", len(removed))
+	return 0
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Print the plan without applying it")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Apply the plan without prompting")
+	syncCmd.Flags().BoolVar(&syncPrune, "prune", false, "Remove packages voltig installed that are no longer in the config")
+	addProfileFlag(syncCmd)
+	rootCmd.AddCommand(syncCmd)
+}
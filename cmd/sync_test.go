@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+	"voltig/internal/models"
+	"voltig/internal/state"
+)
+
+// fakeManager tracks installed versions in memory. Installs of a picked
+// version install that version; everything else installs 9.9.9.
+type fakeManager struct {
+	installed map[string]string
+	ops       []string
+}
+
+func (m *fakeManager) Install(_ context.Context, pkg models.Package, _ func(string)) error {
+	m.ops = append(m.ops, "install "+pkg.Name[0])
+	m.installed[pkg.Name[0]] = "9.9.9"
+	if pkg.Version != "" && pkg.Version != "latest" {
+		m.installed[pkg.Name[0]] = pkg.Version
+	}
+	return nil
+}
+
+func (m *fakeManager) Update(_ context.Context, pkg models.Package) error {
+	m.ops = append(m.ops, "upgrade "+pkg.Name[0])
+	m.installed[pkg.Name[0]] = "9.9.9"
+	return nil
+}
+
+func (m *fakeManager) Remove(_ context.Context, pkg models.Package, _ func(string)) error {
+	m.ops = append(m.ops, "remove "+pkg.Name[0])
+	delete(m.installed, pkg.Name[0])
+	return nil
+}
+
+func (m *fakeManager) GetStatus(_ context.Context, pkg models.Package) ([]models.PackageStatus, error) {
+	var statuses []models.PackageStatus
+	for _, name := range pkg.Name {
+		status := models.PackageStatus{Name: name, Status: "missing"}
+		if v, ok := m.installed[name]; ok {
+			status = models.PackageStatus{Name: name, Status: "installed", Version: models.ParseVersion(v, "fake")}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *fakeManager) IsAvailable() bool { return true }
+
+func syncFixture(t *testing.T) (*config.PackageConfig, *fakeManager, *state.State) {
+	t.Helper()
+	setTestLogger(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	cfg := &config.PackageConfig{
+		Path: filepath.Join(dir, "voltig.yml"),
+		Packages: []config.Package{
+			{Name: []string{"jq"}, Manager: "brew", Version: "latest"},
+			{Name: []string{"node"}, Manager: "brew", Version: "^20"},
+			{Name: []string{"go"}, Manager: "brew", Version: "^1.22"},
+		},
+	}
+	m := &fakeManager{installed: map[string]string{"node": "18.19.0", "go": "1.22.1", "old": "1.0", "mine": "1.0"}}
+	st, _ := state.Load(filepath.Join(dir, "state.json"))
+	st.Own("brew", []string{"old"}, "", cfg.Path)
+	st.Own("brew", []string{"mine"}, "", filepath.Join(dir, "other", "voltig.yml"))
+	return cfg, m, st
+}
+
+func TestPlanSync(t *testing.T) {
+	cfg, m, st := syncFixture(t)
+	plan, err := planSync(context.Background(), cfg, m, st, true)
+	if err != nil {
+		t.Fatalf("planSync failed: %v", err)
+	}
+	var got []string
+	for _, a := range plan {
+		got = append(got, a.Kind+" "+a.Pkg.Name[0])
+	}
+	want := "install jq, upgrade node, remove old"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("plan = %q, want %q", strings.Join(got, ", "), want)
+	}
+}
+
+func TestRunSync(t *testing.T) {
+	t.Cleanup(func() { syncDryRun, syncPrune = false, false })
+
+	cfg, m, st := syncFixture(t)
+	syncDryRun, syncPrune = true, true
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := runSync(context.Background(), cfg, m, st, p, &out); code != 0 || len(m.ops) != 0 {
+		t.Fatalf("dry run: code=%d ops=%v", code, m.ops)
+	}
+	if !strings.Contains(out.String(), "- remove  old") {
+		t.Errorf("expected plan output, got:\n%s", out.String())
+	}
+
+	syncDryRun = false
+	p = &prompter{in: bufio.NewReader(strings.NewReader("n\n")), out: &out}
+	if code := runSync(context.Background(), cfg, m, st, p, &out); code == 0 || len(m.ops) != 0 {
+		t.Fatalf("declined: code=%d ops=%v", code, m.ops)
+	}
+
+	p.assumeYes = true
+	if code := runSync(context.Background(), cfg, m, st, p, &out); code != 0 {
+		t.Fatalf("apply: code=%d", code)
+	}
+	if strings.Join(m.ops, ", ") != "install jq, upgrade node, remove old" {
+		t.Errorf("unexpected operations: %v", m.ops)
+	}
+	if !st.Owns("brew", "jq") || st.Owns("brew", "old") || !st.Owns("brew", "mine") {
+		t.Errorf("unexpected ownership: %+v", st.Packages)
+	}
+	if _, ok := m.installed["mine"]; !ok {
+		t.Error("package owned by another config must not be pruned")
+	}
+}
+
+func TestRunSync_Cancelled(t *testing.T) {
+	cfg, m, st := syncFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := runSync(ctx, cfg, m, st, p, &out); code != exitCancelled || len(m.ops) != 0 {
+		t.Fatalf("cancelled sync: code=%d ops=%v", code, m.ops)
+	}
+	if st.Owns("brew", "jq") {
+		t.Error("cancelled install must not be recorded as owned")
+	}
+}
+
+func TestRunSync_ReinstallNotOwned(t *testing.T) {
+	cfg, m, st := syncFixture(t)
+	cfg.Packages = append(cfg.Packages, config.Package{Name: []string{"python"}, Manager: "brew", Version: "~3.11"})
+	m.installed["python"] = "3.12.1"
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := runSync(context.Background(), cfg, m, st, p, &out); code != 0 {
+		t.Fatalf("apply: code=%d", code)
+	}
+	if !strings.Contains(strings.Join(m.ops, ", "), "install python") {
+		t.Fatalf("expected python to be reinstalled, ops: %v", m.ops)
+	}
+	if st.Owns("brew", "python") {
+		t.Error("package installed before sync must not be recorded as owned")
+	}
+	if !st.Owns("brew", "jq") {
+		t.Error("missing package installed by sync must be owned")
+	}
+}
+
+func TestPlanSync_PruneKeepsListedNames(t *testing.T) {
+	cfg, m, st := syncFixture(t)
+	st.Own("brew", []string{"node", "npm"}, "^20", cfg.Path)
+	cfg.Packages = append(cfg.Packages[:1], config.Package{Name: []string{"npm"}, Manager: "brew"})
+	m.installed["npm"] = "10.2.4"
+	plan, err := planSync(context.Background(), cfg, m, st, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var removes []string
+	for _, a := range plan {
+		if a.Kind == actionRemove {
+			removes = append(removes, strings.Join(a.Pkg.Name, ","))
+		}
+	}
+	if strings.Join(removes, " ") != "node old" {
+		t.Errorf("expected only node and old to be pruned, got %v", removes)
+	}
+}
+
+func TestRunSync_UpgradeWithinConstraint(t *testing.T) {
+	cfg, fake, st := syncFixture(t)
+	m := &listingFake{fakeManager: fake, versions: map[string][]string{"jq": {"1.7.1"}, "node": {"22.3.0", "20"}, "go": {"1.22.1"}}}
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out, assumeYes: true}
+	if code := runSync(context.Background(), cfg, m, st, p, &out); code != 0 {
+		t.Fatalf("apply: code=%d", code)
+	}
+	// An unbounded upgrade would take node to 22, outside ^20
+	if strings.Join(m.ops, ", ") != "install jq, install node" || m.installed["node"] != "20" {
+		t.Errorf("unexpected operations %v, node %s", m.ops, m.installed["node"])
+	}
+	plan, err := planSync(context.Background(), cfg, m, st, false)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("sync did not converge: %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"voltig/internal/journal"
+	"voltig/internal/manager"
+	"voltig/internal/models"
+	"voltig/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var undoYes bool
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [id]",
+	Short: "Reverse a recorded package operation",
+	Long: `Reverse an operation from ` + "`voltig history`" + `: an install is undone by
+removing the package, a remove or update by installing the version that was
+installed before, where the package manager can still install it.
+
+Without an id, the latest successful operation that hasn't been undone yet is
+reversed, so running undo repeatedly walks back through the history. Undoing
+is recorded in the journal too.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m := manager.ForOS()
+		if m == nil {
+			logger.Error("No supported package manager found for this OS")
+			os.Exit(1)
+		}
+		j := journal.Open(journal.DefaultPath())
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, assumeYes: undoYes}
+		if code := runUndo(cmd.Context(), m, j, args, p, os.Stdout); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+/*
+undoTarget returns the entry to undo: the one with the id in args, or the
+latest successful operation that is neither undone nor an undo itself.
+*/
+func undoTarget(entries []journal.Entry, args []string) (journal.Entry, error) {
+	if len(args) == 0 {
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.Status == journal.StatusOK && e.Undoes == 0 && journal.UndoneBy(entries, e.ID) == 0 {
+				return e, nil
+			}
+		}
+		return journal.Entry{}, fmt.Errorf("nothing to undo")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return journal.Entry{}, fmt.Errorf("invalid id %q", args[0])
+	}
+	for _, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		if by := journal.UndoneBy(entries, id); by != 0 {
+			return journal.Entry{}, fmt.Errorf("operation #%d was already undone by #%d", id, by)
+		}
+		return e, nil
+	}
+	return journal.Entry{}, fmt.Errorf("no operation #%d in the journal", id)
+}
+
+/*
+undoPlan returns the operation reversing e. Installs of a missing package are
+reversed by removing it; everything else by installing the previous version,
+which fails if m can no longer install it. Journaled versions are installed
+ones, so they are mapped like a snapshot's by manager.LockedVersion.
+*/
+func undoPlan(ctx context.Context, m manager.PackageManager, e journal.Entry) (string, models.Package, error) {
+	pkg := models.Package{Name: []string{e.Package}, Manager: e.Manager}
+	switch {
+	case e.Status != journal.StatusOK:
+		return "", pkg, fmt.Errorf("operation #%d %s; there is nothing to undo", e.ID, e.Status)
+	case e.Before == e.After:
+		return "", pkg, fmt.Errorf("operation #%d didn't change %s", e.ID, e.Package)
+	case e.Before == "":
+		return manager.OpRemove, pkg, nil
+	}
+	pkg.Version = e.Before
+	picked, err := manager.LockedVersion(ctx, m, pkg)
+	if err != nil {
+		return "", pkg, fmt.Errorf("cannot reinstall %s %s: %w", e.Package, e.Before, err)
+	}
+	// "latest" would leave a newer version installed after an update
+	if e.After != "" && picked.Version == "latest" {
+		return "", pkg, fmt.Errorf("%s cannot downgrade %s to %s", e.Manager, e.Package, e.Before)
+	}
+	return manager.OpInstall, picked, nil
+}
+
+// runUndo reverses the selected journal entry and returns the exit code.
+func runUndo(ctx context.Context, m manager.PackageManager, j *journal.Journal, args []string, p *prompter, out io.Writer) int {
+	entries, err := j.Entries()
+	if err != nil {
+		logger.Error("Failed to read journal", "error", err)
+		return 1
+	}
+	e, err := undoTarget(entries, args)
+	if err != nil {
+		logger.Error("Cannot undo", "error", err)
+		return 1
+	}
+	op, pkg, err := undoPlan(ctx, m, e)
+	if err != nil {
+		logger.Error("Cannot undo", "error", err)
+		return 1
+	}
+	desc := fmt.Sprintf("%s %s", op, e.Package)
+	if op == manager.OpInstall {
+		desc += " " + e.Before
+	}
+	_, _ = fmt.Fprintf(out, "Undo #%d (%s %s, %s): %s\n", e.ID, e.Op, e.Package, e.Time.Local().Format("2006-01-02 15:04"), desc)
+	if !p.confirm("Proceed?", true) {
+		logger.Info("Undo cancelled")
+		return 1
+	}
+
+	opFunc := m.Install
+	if op == manager.OpRemove {
+		opFunc = m.Remove
+	}
+	pkgs := []models.Package{pkg}
+	done := journalOp(ctx, m, op, pkgs, e.ID)
+	succeeded, failed, cancelled := manager.PkgOperation(ctx, "Undoing", "undone", pkgs, opFunc)
+	done(succeeded, failed, cancelled)
+	if op == manager.OpRemove {
+		forgetOwnership(pkgs, succeeded)
+	}
+	reportCancelled(ctx, cancelled)
+	switch {
+	case len(cancelled) > 0:
+		return exitCancelled
+	case len(failed) > 0:
+		logger.Error("Failed to undo", "id", e.ID, "package", e.Package)
+		return 1
+	}
+	logger.Info("Undone", "id", e.ID, "operation", desc)
+	return 0
+}
+
+func init() {
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "Undo without prompting")
+	rootCmd.AddCommand(undoCmd)
+}
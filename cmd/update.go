@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"voltig/config"
 	"voltig/internal/manager"
@@ -15,7 +16,8 @@ var updateCmd = &cobra.Command{
 	Use:   "update [pkg_name...]",
 	Short: "Update specific or all packages",
 	Args:  cobra.ArbitraryArgs,
-	Run: func(_ *cobra.Command, args []string) {
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		cfg, err := config.LoadConfig(configFile)
 		if err != nil {
 			logger.Error("Failed to load config", "error", err)
@@ -26,48 +28,56 @@ var updateCmd = &cobra.Command{
 			logger.Error("No supported package manager found for this OS")
 			os.Exit(1)
 		}
-		var targetPkgs []config.Package
-		var notFound []string
+		pkgs, err := profilePackages(cfg, args)
+		if err != nil {
+			logger.Error("Failed to resolve profile", "error", err)
+			os.Exit(1)
+		}
 		// Determine which packages to update
 		if len(args) == 0 {
-			// Update all packages from config
-			targetPkgs = cfg.Packages
-			logger.Info("Updating all packages from config", "count", len(targetPkgs))
+			logger.Info("Updating all packages from config", "count", len(pkgs))
 		} else {
-			// Update only specified packages
 			logger.Info("Updating specified packages", "packages", args)
-			for _, arg := range args {
-				found := false
-				for _, pkg := range cfg.Packages {
-					// Check if the package name is in the list of names
-					for _, name := range pkg.Name {
-						if name == arg {
-							targetPkgs = append(targetPkgs, pkg)
-							found = true
-							break
-						}
-					}
-					if found {
-						break
-					}
-				}
-				if !found {
-					logger.Error("Package not found in config", "package", arg)
-					notFound = append(notFound, arg)
-				}
-			}
 		}
-		// Update the target packages
+		sel := selectPackages(pkgs, args)
+		targetPkgs, notFound := sel.Targets, sel.NotFound
+		var pkgModels []models.Package
 		for _, pkg := range targetPkgs {
-			_ = m.Update(models.ToModel(pkg))
+			pkgModels = append(pkgModels, models.ToModel(pkg))
+		}
+		if dryRun {
+			os.Exit(printDryRun(ctx, m, manager.OpUpdate, pkgModels, notFound))
+		}
+		// Update the target packages
+		done := journalOp(ctx, m, manager.OpUpdate, pkgModels, 0)
+		updated, failed, cancelled := updatePackages(ctx, m, pkgModels)
+		done(updated, failed, cancelled)
+		reportCancelled(ctx, cancelled)
+		if len(cancelled) > 0 {
+			os.Exit(exitCancelled)
+		}
+		if len(failed) > 0 {
+			logger.Error("Failed to update packages", "packages", failed)
 		}
 		if len(notFound) > 0 {
-			logger.Error("Failed to update packages", "packages", notFound)
+			logger.Error("Packages not found in config", "packages", notFound)
+		}
+		if len(failed) > 0 || len(notFound) > 0 {
 			os.Exit(1)
 		}
 	},
 }
 
+// updatePackages updates pkgs through manager.PkgOperation, so package
+// timeouts and retries apply as they do to installs and removes.
+func updatePackages(ctx context.Context, m manager.PackageManager, pkgs []models.Package) (updated, failed, cancelled []string) {
+	return manager.PkgOperation(ctx, "Updating", "updated", pkgs, func(ctx context.Context, pkg models.Package, _ func(string)) error {
+		return m.Update(ctx, pkg)
+	})
+}
+
 func init() {
+	addProfileFlag(updateCmd)
+	addDryRunFlag(updateCmd)
 	rootCmd.AddCommand(updateCmd)
 }
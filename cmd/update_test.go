@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"voltig/internal/models"
+)
+
+// slowUpdater is a fakeManager whose updates run until ctx is done.
+type slowUpdater struct{ *fakeManager }
+
+func (m slowUpdater) Update(ctx context.Context, pkg models.Package) error {
+	m.ops = append(m.ops, "upgrade "+pkg.Name[0])
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestUpdatePackages_Timeout(t *testing.T) {
+	setTestLogger(t)
+	m := slowUpdater{&fakeManager{installed: map[string]string{"node": "20.0.0"}}}
+	pkgs := []models.Package{{Name: []string{"node"}, Manager: "brew", Timeout: time.Millisecond}}
+	updated, failed, cancelled := updatePackages(context.Background(), m, pkgs)
+	if len(updated) != 0 || len(failed) != 1 || len(cancelled) != 0 {
+		t.Errorf("package timeout not applied: updated=%v failed=%v cancelled=%v", updated, failed, cancelled)
+	}
+}
+
+// flakyUpdater is a fakeManager whose first update fails.
+type flakyUpdater struct {
+	*fakeManager
+	failures int
+}
+
+func (m *flakyUpdater) Update(ctx context.Context, pkg models.Package) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection reset")
+	}
+	return m.fakeManager.Update(ctx, pkg)
+}
+
+func TestUpdatePackages_Retries(t *testing.T) {
+	setTestLogger(t)
+	m := &flakyUpdater{fakeManager: &fakeManager{installed: map[string]string{"node": "20.0.0"}}, failures: 1}
+	pkgs := []models.Package{{Name: []string{"node"}, Manager: "brew", Retries: 1, RetryBackoff: time.Millisecond}}
+	updated, failed, _ := updatePackages(context.Background(), m, pkgs)
+	if len(updated) != 1 || len(failed) != 0 || m.installed["node"] != "9.9.9" {
+		t.Errorf("transient failure not retried: updated=%v failed=%v", updated, failed)
+	}
+}
@@ -5,9 +5,11 @@ package config
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,14 +25,151 @@ type CustomCommand struct {
 	WorkDir     string   `yaml:"workDir,omitempty"`
 	Environment []string `yaml:"environment,omitempty"`
 	Shell       string   `yaml:"shell,omitempty"`
+	// Platforms and When restrict the command to matching hosts.
+	Platforms StringList `yaml:"platforms,omitempty"`
+	When      *Condition `yaml:"when,omitempty"`
+	// Commands holds nested sub-commands, e.g. `db: {commands: {migrate: ...}}`.
+	Commands map[string]CustomCommand `yaml:"commands,omitempty"`
+}
+
+// Runnable reports whether the command has something to execute on its own,
+// as opposed to being a pure namespace for nested commands.
+func (c CustomCommand) Runnable() bool {
+	return c.Command != "" || c.Script != ""
+}
+
+/*
+CommandEntry is a custom command resolved to its full namespace path.
+A key of `db:migrate` or a nested `db: {commands: {migrate: ...}}` both
+resolve to the path ["db", "migrate"].
+*/
+type CommandEntry struct {
+	Path    []string
+	Command CustomCommand
+}
+
+// Name returns the colon-separated name of the entry, e.g. "db:migrate".
+func (e CommandEntry) Name() string {
+	return strings.Join(e.Path, ":")
+}
+
+/*
+FlattenCommands resolves namespaced (`db:migrate`) and nested command
+definitions into a list of entries sorted by path. Parents always sort before
+their children. Nested Commands are cleared on the returned entries.
+*/
+func FlattenCommands(cmds map[string]CustomCommand) ([]CommandEntry, error) {
+	seen := make(map[string]struct{})
+	var entries []CommandEntry
+	var walk func(prefix []string, cmds map[string]CustomCommand) error
+	walk = func(prefix []string, cmds map[string]CustomCommand) error {
+		for key, c := range cmds {
+			path := append(append([]string{}, prefix...), strings.Split(key, ":")...)
+			for _, segment := range path {
+				if strings.TrimSpace(segment) == "" || strings.ContainsAny(segment, " \t") {
+					return fmt.Errorf("invalid command name %q", strings.Join(path, ":"))
+				}
+			}
+			name := strings.Join(path, ":")
+			if _, exists := seen[name]; exists {
+				return fmt.Errorf("duplicate command %q", name)
+			}
+			seen[name] = struct{}{}
+			nested := c.Commands
+			c.Commands = nil
+			entries = append(entries, CommandEntry{Path: path, Command: c})
+			if err := walk(path, nested); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(nil, cmds); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Path, entries[j].Path
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return entries, nil
+}
+
+// ProtectedCommands lists the built-in command names user commands may not override.
+var ProtectedCommands = map[string]struct{}{
+	"install": {}, "update": {}, "remove": {}, "status": {}, "tui": {}, "help": {}, "completion": {}, "lint": {}, "config": {}, "scan": {}, "version": {}, "profiles": {}, "schema": {}, "init": {}, "add": {}, "lock": {}, "sync": {}, "outdated": {}, "history": {}, "undo": {}, "snapshot": {}, "diff": {}, "doctor": {},
+}
+
+// ValidateCommands resolves cmds like FlattenCommands and checks that no
+// top-level name collides with a core command.
+func ValidateCommands(cmds map[string]CustomCommand) ([]CommandEntry, error) {
+	entries, err := FlattenCommands(cmds)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if _, found := ProtectedCommands[e.Path[0]]; found {
+			return nil, fmt.Errorf("command %q is protected by core CLI and cannot be overridden", e.Path[0])
+		}
+		if e.Path[len(e.Path)-1] == "help" {
+			return nil, fmt.Errorf("command %q is protected by core CLI and cannot be overridden", e.Name())
+		}
+	}
+	return entries, nil
 }
 
 /*
 PackageConfig represents the configuration for packages.
 */
 type PackageConfig struct {
-	Packages []Package                `yaml:"packages"`
-	Commands map[string]CustomCommand `yaml:"commands"`
+	Packages       []Package                `yaml:"packages"`
+	Commands       map[string]CustomCommand `yaml:"commands"`
+	Profiles       map[string]Profile       `yaml:"profiles,omitempty"`
+	DefaultProfile string                   `yaml:"default_profile,omitempty"`
+	Include        StringList               `yaml:"include,omitempty"`
+	Vars           map[string]string        `yaml:"vars,omitempty"`
+	EnvFile        StringList               `yaml:"env_file,omitempty"`
+	// Retries and RetryBackoff are the defaults for packages that don't set
+	// their own.
+	Retries      int    `yaml:"retries,omitempty"`
+	RetryBackoff string `yaml:"retry_backoff,omitempty"`
+
+	// Path is the resolved project config file and Sources records which
+	// file each entry was loaded from. Both are set by LoadConfig.
+	Path    string   `yaml:"-"`
+	Sources *Sources `yaml:"-"`
+	// Env holds the values loaded from EnvFile and Unresolved the `${...}`
+	// references interpolation could not resolve.
+	Env        map[string]string `yaml:"-"`
+	Unresolved []UnresolvedRef   `yaml:"-"`
+
+	// positions of the entries of a single decoded file, see recordPositions.
+	positions map[string]Position
+}
+
+/*
+PositionOf returns where key (e.g. "commands.build.command" or "packages.node")
+was defined, falling back to shorter prefixes of key and finally to the
+project file.
+*/
+func (c *PackageConfig) PositionOf(key string) Position {
+	if c.Sources != nil {
+		for k := key; k != ""; {
+			if pos, ok := c.Sources.Positions[k]; ok {
+				return pos
+			}
+			i := strings.LastIndex(k, ".")
+			if i < 0 {
+				break
+			}
+			k = k[:i]
+		}
+	}
+	return Position{File: c.Path}
 }
 
 /*
@@ -56,6 +195,38 @@ func (p *PackageName) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+/*
+StringList represents a value that can be either a single string or an array of strings.
+*/
+type StringList []string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for StringList.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err == nil {
+		*l = []string{s}
+		return nil
+	}
+	var a []string
+	if err := value.Decode(&a); err != nil {
+		return fmt.Errorf("value must be a string or an array of strings: %w", err)
+	}
+	*l = a
+	return nil
+}
+
+/*
+Condition restricts a package or command to hosts matching all of the given
+fields. Each field matches if any of its values matches.
+*/
+type Condition struct {
+	OS      StringList `yaml:"os,omitempty"`
+	Arch    StringList `yaml:"arch,omitempty"`
+	Distro  StringList `yaml:"distro,omitempty"`
+	Env     StringList `yaml:"env,omitempty"`     // environment variable is set
+	Command StringList `yaml:"command,omitempty"` // binary exists in PATH
+}
+
 /*
 Package represents a package to be installed.
 */
@@ -65,40 +236,160 @@ type Package struct {
 	Version      string      `yaml:"version"`
 	Optional     bool        `yaml:"optional"`
 	Dependencies []string    `yaml:"dependencies"`
+	Platforms    StringList  `yaml:"platforms,omitempty"`
+	When         *Condition  `yaml:"when,omitempty"`
+	Groups       StringList  `yaml:"groups,omitempty"`
+	Timeout      string      `yaml:"timeout,omitempty"` // e.g. 10m; bounds each install/update/remove
+	// Retries is how often a failed operation is retried (nil inherits the
+	// top-level default); RetryBackoff is the delay before the first retry,
+	// doubled for each further one.
+	Retries      *int   `yaml:"retries,omitempty"`
+	RetryBackoff string `yaml:"retry_backoff,omitempty"`
+	// Hooks are shell commands run like custom commands, in order, before and
+	// after the package is installed or removed. A failing pre hook aborts
+	// the operation.
+	PreInstall  StringList `yaml:"pre_install,omitempty"`
+	PostInstall StringList `yaml:"post_install,omitempty"`
+	PreRemove   StringList `yaml:"pre_remove,omitempty"`
+	PostRemove  StringList `yaml:"post_remove,omitempty"`
 }
 
 /*
 LoadConfig loads the configuration from a YAML file.
+
+The result merges, from lowest to highest precedence: the user-level config
+(~/.config/voltig/config.yml), the project file together with its `include:`
+files, and an untracked `<name>.local.yml` next to the project file.
+See mergeConfig for how entries from different layers are combined.
+`${VAR}` references are expanded once all layers are merged.
+
+Decoding is strict: unknown fields and malformed values in any file make
+LoadConfig fail with a Diagnostics error listing every problem.
 */
 func LoadConfig(path string) (*PackageConfig, error) {
-	// Try direct open first
-	f, err := os.Open(path)
+	cfg, diags, err := LoadConfigDiagnostics(path)
 	if err != nil {
-		// If not found, search upward for voltig.yml
-		if os.IsNotExist(err) {
-			if found, ok := findConfigInParents("voltig.yml"); ok {
-				f, err = os.Open(found)
+		return nil, err
+	}
+	if diags.HasErrors() {
+		var errs Diagnostics
+		for _, d := range diags {
+			if d.Severity == SeverityError {
+				errs = append(errs, d)
 			}
 		}
-		if f == nil || err != nil {
-			return nil, err
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+/*
+LoadConfigDiagnostics loads the configuration like LoadConfig but returns the
+merged config together with every diagnostic instead of failing on the first
+invalid file. The error is only set when no config file could be found.
+*/
+func LoadConfigDiagnostics(path string) (*PackageConfig, Diagnostics, error) {
+	resolved, err := ResolveConfigPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := &loader{cfg: &PackageConfig{Path: resolved, Sources: newSources()}}
+	if userPath := userConfigPath(); userPath != "" && userPath != resolved {
+		if _, err := os.Stat(userPath); err == nil {
+			l.loadLayer(userPath, nil)
 		}
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to close file: %v\n", err)
+	l.loadLayer(resolved, nil)
+	if localPath := LocalConfigPath(resolved); localPath != resolved {
+		if _, err := os.Stat(localPath); err == nil {
+			l.loadLayer(localPath, nil)
 		}
-	}()
-	var cfg PackageConfig
-	dec := yaml.NewDecoder(f)
-	if err := dec.Decode(&cfg); err != nil {
-		if err == io.EOF {
-			// Return empty config if file is empty
-			return &PackageConfig{}, nil
+	}
+	if err := l.cfg.interpolate(); err != nil {
+		l.diags = append(l.diags, Diagnostic{Position: Position{File: resolved}, Severity: SeverityError, Message: err.Error()})
+	}
+	l.cfg.applyRetryDefaults()
+	l.diags.Sort()
+	return l.cfg, l.diags, nil
+}
+
+// applyRetryDefaults gives packages without their own retry settings the
+// top-level retries and retry_backoff, recording them in Sources.Inherited.
+func (c *PackageConfig) applyRetryDefaults() {
+	inherit := func(pkg *Package, field string) {
+		if c.Sources != nil && len(pkg.Name) > 0 {
+			c.Sources.Inherited["packages."+pkg.Name[0]+"."+field] = true
+		}
+	}
+	for i := range c.Packages {
+		pkg := &c.Packages[i]
+		if pkg.Retries == nil && c.Retries != 0 {
+			retries := c.Retries
+			pkg.Retries = &retries
+			inherit(pkg, "retries")
+		}
+		if pkg.RetryBackoff == "" && c.RetryBackoff != "" {
+			pkg.RetryBackoff = c.RetryBackoff
+			inherit(pkg, "retry_backoff")
+		}
+	}
+}
+
+// Inherited reports whether the setting at key, e.g. "packages.jq.retries",
+// was filled in from a top-level default rather than set explicitly.
+func (c *PackageConfig) Inherited(key string) bool {
+	return c.Sources != nil && c.Sources.Inherited[key]
+}
+
+/*
+ResolveConfigPath returns the path of the project config, searching upward for
+voltig.yml if path does not exist.
+*/
+func ResolveConfigPath(path string) (string, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return path, nil
+	}
+	if os.IsNotExist(err) {
+		if found, ok := findConfigInParents("voltig.yml"); ok {
+			return found, nil
+		}
+	}
+	return "", err
+}
+
+/*
+decodeFile decodes and strictly validates a single YAML file without
+resolving includes. An empty file yields an empty config. The returned config
+is nil if the file could not be decoded at all.
+*/
+func decodeFile(path string) (*PackageConfig, Diagnostics) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Diagnostics{{Position: Position{File: path}, Severity: SeverityError, Message: err.Error()}}
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, yamlErrorDiagnostics(path, err)
+	}
+	cfg := &PackageConfig{positions: make(map[string]Position)}
+	if len(root.Content) == 0 {
+		// Return empty config if file is empty
+		return cfg, nil
+	}
+	doc := root.Content[0]
+	diags := checkNode(path, doc, reflect.TypeOf(PackageConfig{}))
+	if err := doc.Decode(cfg); err != nil {
+		if len(diags) == 0 {
+			diags = yamlErrorDiagnostics(path, err)
+		}
+		if _, partial := err.(*yaml.TypeError); !partial {
+			return nil, diags
 		}
-		return nil, err
 	}
-	return &cfg, nil
+	diags = append(diags, checkPackages(path, doc, cfg)...)
+	recordPositions(path, doc, cfg.positions)
+	return cfg, diags
 }
 
 /*
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -104,3 +105,132 @@ func TestLoadConfigEmpty(t *testing.T) {
 		t.Errorf("Expected 0 packages, got %d", len(cfg.Packages))
 	}
 }
+
+func TestFlattenCommands(t *testing.T) {
+	cmds := map[string]CustomCommand{
+		"build":      {Summary: "Build", Command: "go build"},
+		"db:migrate": {Summary: "Migrate", Command: "migrate up"},
+		"db": {
+			Summary: "Database",
+			Commands: map[string]CustomCommand{
+				"seed": {Summary: "Seed", Command: "seed"},
+			},
+		},
+	}
+	entries, err := FlattenCommands(cmds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"build", "db", "db:migrate", "db:seed"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+	if entries[1].Command.Runnable() {
+		t.Errorf("expected db namespace to not be runnable")
+	}
+	if entries[1].Command.Commands != nil {
+		t.Errorf("expected nested commands to be cleared on flattened entries")
+	}
+}
+
+func TestFlattenCommandsDuplicate(t *testing.T) {
+	cmds := map[string]CustomCommand{
+		"db:migrate": {Command: "a"},
+		"db": {Commands: map[string]CustomCommand{
+			"migrate": {Command: "b"},
+		}},
+	}
+	if _, err := FlattenCommands(cmds); err == nil {
+		t.Errorf("expected duplicate command error")
+	}
+	if _, err := FlattenCommands(map[string]CustomCommand{"db::x": {Command: "a"}}); err == nil {
+		t.Errorf("expected invalid name error")
+	}
+}
+
+func TestLoadConfigConditions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	yamlContent := `
+packages:
+  - name: mas
+    manager: brew
+    platforms: macos
+  - name: docker-compose
+    manager: brew
+    when:
+      os: [linux]
+      command: docker
+commands:
+  open:
+    summary: Open
+    command: open .
+    platforms: [darwin/arm64]
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(cfg.Packages[0].Platforms) != 1 || cfg.Packages[0].Platforms[0] != "macos" {
+		t.Errorf("expected scalar platforms to decode, got %v", cfg.Packages[0].Platforms)
+	}
+	when := cfg.Packages[1].When
+	if when == nil || when.OS[0] != "linux" || when.Command[0] != "docker" {
+		t.Errorf("unexpected when condition: %+v", when)
+	}
+	if cfg.Commands["open"].Platforms[0] != "darwin/arm64" {
+		t.Errorf("unexpected command platforms: %v", cfg.Commands["open"].Platforms)
+	}
+}
+
+func TestLoadConfigRetryDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	yamlContent := `
+retries: 3
+retry_backoff: 5s
+packages:
+  - name: jq
+    manager: brew
+  - name: node
+    manager: brew
+    retries: 0
+    retry_backoff: 1m
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	jq, node := cfg.Packages[0], cfg.Packages[1]
+	if jq.Retries == nil || *jq.Retries != 3 || jq.RetryBackoff != "5s" {
+		t.Errorf("expected jq to inherit the defaults, got %v %q", jq.Retries, jq.RetryBackoff)
+	}
+	if node.Retries == nil || *node.Retries != 0 || node.RetryBackoff != "1m" {
+		t.Errorf("expected node to keep its own settings, got %v %q", node.Retries, node.RetryBackoff)
+	}
+}
+
+func TestValidateCommands_Protected(t *testing.T) {
+	cases := []map[string]CustomCommand{
+		{"install": {Command: "echo"}},
+		{"scan:deep": {Command: "echo"}},
+		{"db": {Commands: map[string]CustomCommand{"help": {Command: "echo"}}}},
+		{"doctor": {Command: "echo"}},
+	}
+	for _, cmds := range cases {
+		if _, err := ValidateCommands(cmds); err == nil {
+			t.Errorf("expected protected name error for %v", cmds)
+		}
+	}
+	if _, err := ValidateCommands(map[string]CustomCommand{"db:install": {Command: "echo"}}); err != nil {
+		t.Errorf("expected nested core name to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location in a config file. Line and Column are 1-based; zero
+// means unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String renders the position as file:line:column, omitting unknown parts.
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+}
+
+// Severity of a diagnostic.
+type Severity string
+
+const (
+	// SeverityError makes the config invalid.
+	SeverityError Severity = "error"
+	// SeverityWarning is reported by lint but does not fail loading.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem found in a config file.
+type Diagnostic struct {
+	Position
+	Severity Severity
+	Message  string
+}
+
+// String renders the diagnostic compiler-style: `file:line:col: error: message`.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Position, d.Severity, d.Message)
+}
+
+// Diagnostics is a list of problems. It implements error so LoadConfig can
+// return every problem at once.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders diagnostics by file, line and column.
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		a, b := ds[i].Position, ds[j].Position
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+func errorAt(file string, n *yaml.Node, format string, args ...interface{}) Diagnostic {
+	pos := Position{File: file}
+	if n != nil {
+		pos.Line, pos.Column = n.Line, n.Column
+	}
+	return Diagnostic{Position: pos, Severity: SeverityError, Message: fmt.Sprintf(format, args...)}
+}
+
+// yamlLinePattern extracts the line from yaml.v3 error messages.
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlErrorDiagnostics converts a yaml.v3 syntax or type error into diagnostics.
+func yamlErrorDiagnostics(file string, err error) Diagnostics {
+	var msgs []string
+	if te, ok := err.(*yaml.TypeError); ok {
+		msgs = te.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+	var diags Diagnostics
+	for _, msg := range msgs {
+		d := Diagnostic{Position: Position{File: file}, Severity: SeverityError, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// kindNames describes config types in diagnostics.
+var kindNames = map[reflect.Type]string{
+	reflect.TypeOf(PackageConfig{}): "config",
+	reflect.TypeOf(Package{}):       "package",
+	reflect.TypeOf(CustomCommand{}): "command",
+	reflect.TypeOf(Condition{}):     "condition",
+	reflect.TypeOf(Profile{}):       "profile",
+}
+
+// yamlFields maps the YAML keys of a struct type to their field types.
+func yamlFields(t reflect.Type) (names []string, types map[string]reflect.Type) {
+	types = make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		names = append(names, name)
+		types[name] = f.Type
+	}
+	return names, types
+}
+
+/*
+checkNode validates n against the Go type t, reporting unknown fields and
+values of the wrong shape with their line and column. It is what makes
+config decoding strict.
+*/
+func checkNode(file string, n *yaml.Node, t reflect.Type) Diagnostics {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		// PackageName and StringList accept a string or a list of strings
+		if n.Kind == yaml.SequenceNode {
+			var diags Diagnostics
+			for _, item := range n.Content {
+				if item.Kind != yaml.ScalarNode {
+					diags = append(diags, errorAt(file, item, "expected a string"))
+				}
+			}
+			return diags
+		}
+		if n.Kind != yaml.ScalarNode {
+			return Diagnostics{errorAt(file, n, "expected a string or a list of strings")}
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return Diagnostics{errorAt(file, n, "expected a mapping for %s", kindNames[t])}
+		}
+		names, fields := yamlFields(t)
+		var diags Diagnostics
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q in %s", key.Value, kindNames[t])
+				if s := suggest(key.Value, names); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				diags = append(diags, errorAt(file, key, "%s", msg))
+				continue
+			}
+			diags = append(diags, checkNode(file, value, ft)...)
+		}
+		return diags
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return Diagnostics{errorAt(file, n, "expected a mapping")}
+		}
+		var diags Diagnostics
+		for i := 1; i < len(n.Content); i += 2 {
+			diags = append(diags, checkNode(file, n.Content[i], t.Elem())...)
+		}
+		return diags
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return Diagnostics{errorAt(file, n, "expected a list")}
+		}
+		var diags Diagnostics
+		for _, item := range n.Content {
+			diags = append(diags, checkNode(file, item, t.Elem())...)
+		}
+		return diags
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			return Diagnostics{errorAt(file, n, "expected true or false, got %q", n.Value)}
+		}
+	default:
+		if n.Kind != yaml.ScalarNode {
+			return Diagnostics{errorAt(file, n, "expected a string")}
+		}
+	}
+	return nil
+}
+
+// suggest returns the candidate closest to s if it is a likely typo.
+func suggest(s string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(s), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+/*
+checkPackages reports packages without a name and names listed more than once
+within a single file. Duplicates across files are intentional overrides.
+*/
+func checkPackages(file string, root *yaml.Node, cfg *PackageConfig) Diagnostics {
+	pkgsNode := mappingValue(root, "packages")
+	var diags Diagnostics
+	seen := make(map[string]bool)
+	for i, pkg := range cfg.Packages {
+		var node *yaml.Node
+		if pkgsNode != nil && pkgsNode.Kind == yaml.SequenceNode && i < len(pkgsNode.Content) {
+			node = pkgsNode.Content[i]
+		}
+		if len(pkg.Name) == 0 {
+			diags = append(diags, errorAt(file, node, "package is missing required field \"name\""))
+		}
+		for _, name := range pkg.Name {
+			if seen[name] {
+				diags = append(diags, errorAt(file, mappingValue(node, "name"), "duplicate package name %q", name))
+			}
+			seen[name] = true
+		}
+	}
+	return diags
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+/*
+recordPositions stores the position of every package name, command, profile
+and var in file under keys like "packages.node" or "commands.db.commands.seed".
+*/
+func recordPositions(file string, root *yaml.Node, positions map[string]Position) {
+	at := func(n *yaml.Node) Position {
+		return Position{File: file, Line: n.Line, Column: n.Column}
+	}
+	if pkgs := mappingValue(root, "packages"); pkgs != nil && pkgs.Kind == yaml.SequenceNode {
+		for _, item := range pkgs.Content {
+			name := mappingValue(item, "name")
+			if name == nil {
+				continue
+			}
+			if name.Kind == yaml.ScalarNode {
+				positions["packages."+name.Value] = at(item)
+			}
+			for _, n := range name.Content {
+				positions["packages."+n.Value] = at(item)
+			}
+		}
+	}
+	var walkCommands func(prefix string, n *yaml.Node)
+	walkCommands = func(prefix string, n *yaml.Node) {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := prefix + "." + n.Content[i].Value
+			positions[key] = at(n.Content[i])
+			walkCommands(key+".commands", mappingValue(n.Content[i+1], "commands"))
+		}
+	}
+	walkCommands("commands", mappingValue(root, "commands"))
+	for _, section := range []string{"profiles", "vars"} {
+		if n := mappingValue(root, section); n != nil && n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				positions[section+"."+n.Content[i].Value] = at(n.Content[i])
+			}
+		}
+	}
+	if root != nil && root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			positions[root.Content[i].Value] = at(root.Content[i])
+		}
+	}
+}
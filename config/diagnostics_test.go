@@ -0,0 +1,105 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	writeFile(t, path, `packages:
+  - name: node
+    manger: brew
+  - name: [go, node]
+    manager: brew
+    optional: maybe
+commands:
+  build:
+    summry: Build
+    command: go build
+`)
+	cfg, err := LoadConfig(path)
+	if err == nil || cfg != nil {
+		t.Fatalf("expected strict decoding to fail, got cfg=%v", cfg)
+	}
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		t.Fatalf("expected Diagnostics error, got %T", err)
+	}
+	want := []string{
+		path + `:3:5: error: unknown field "manger" in package (did you mean "manager"?)`,
+		path + `:4:11: error: duplicate package name "node"`,
+		path + `:6:15: error: expected true or false, got "maybe"`,
+		path + `:9:5: error: unknown field "summry" in command (did you mean "summary"?)`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("expected %d diagnostics, got:\n%s", len(want), diags.Error())
+	}
+	for i, d := range diags {
+		if d.String() != want[i] {
+			t.Errorf("diagnostic %d:\n got  %s\n want %s", i, d, want[i])
+		}
+	}
+}
+
+func TestLoadConfigDiagnosticsAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yml"), "packages:\n  - name: jq\n    verison: 1\n")
+	path := filepath.Join(dir, "voltig.yml")
+	writeFile(t, path, "include: base.yml\nprofiles:\n  x:\n    extend: y\n")
+	cfg, diags, err := LoadConfigDiagnostics(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diags) != 2 || !strings.HasSuffix(diags[0].File, "base.yml") || diags[1].Line != 4 {
+		t.Errorf("expected one diagnostic per file, got:\n%s", diags.Error())
+	}
+	if cfg == nil || len(cfg.Packages) != 1 {
+		t.Errorf("expected merged config despite diagnostics, got %+v", cfg)
+	}
+	if pos := cfg.PositionOf("packages.jq.version"); pos.Line != 2 || !strings.HasSuffix(pos.File, "base.yml") {
+		t.Errorf("unexpected position %v", pos)
+	}
+}
+
+func TestLoadConfigSyntaxErrorPosition(t *testing.T) {
+	_, err := LoadConfig("invalid.yml")
+	diags, ok := err.(Diagnostics)
+	if !ok || len(diags) != 1 || diags[0].Line != 1 {
+		t.Errorf("expected a single diagnostic on line 1, got %v", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"name", "manager", "version", "optional"}
+	if got := suggest("manger", candidates); got != "manager" {
+		t.Errorf("expected manager, got %q", got)
+	}
+	if got := suggest("something", candidates); got != "" {
+		t.Errorf("expected no suggestion, got %q", got)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	props := schema["properties"].(map[string]interface{})
+	for _, key := range []string{"packages", "commands", "profiles", "vars", "include"} {
+		if _, ok := props[key]; !ok {
+			t.Errorf("expected property %q in schema", key)
+		}
+	}
+	defs := schema["$defs"].(map[string]interface{})
+	pkg := defs["Package"].(map[string]interface{})
+	if pkg["additionalProperties"] != false {
+		t.Errorf("expected Package to reject unknown fields")
+	}
+	if req := pkg["required"].([]string); len(req) != 2 {
+		t.Errorf("expected name and manager to be required, got %v", req)
+	}
+	cmdProps := defs["CustomCommand"].(map[string]interface{})["properties"].(map[string]interface{})
+	nested := cmdProps["commands"].(map[string]interface{})["additionalProperties"].(map[string]interface{})
+	if nested["$ref"] != "#/$defs/CustomCommand" {
+		t.Errorf("expected nested commands to reference CustomCommand, got %v", nested)
+	}
+}
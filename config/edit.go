@@ -0,0 +1,353 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Document is a single config file opened for editing. Edits are applied to the
+yaml.Node tree so comments and key order survive; the file is re-encoded with
+its own indentation width on Save.
+*/
+type Document struct {
+	Path   string
+	root   yaml.Node
+	indent int
+}
+
+// OpenDocument reads path for editing. A missing or empty file yields an
+// empty mapping document.
+func OpenDocument(path string) (*Document, error) {
+	d := &Document{Path: path, indent: 2}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &d.root); err != nil {
+		return nil, yamlErrorDiagnostics(path, err)
+	}
+	if len(d.root.Content) == 0 {
+		d.root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if d.root.Content[0].Kind != yaml.MappingNode {
+		return nil, Diagnostics{errorAt(path, d.root.Content[0], "expected a mapping at the top level")}
+	}
+	d.indent = detectIndent(data)
+	return d, nil
+}
+
+var indentPattern = regexp.MustCompile(`(?m)^( +)\S`)
+
+// detectIndent returns the smallest indentation used in data, defaulting to 2.
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, m := range indentPattern.FindAllSubmatch(data, -1) {
+		if n := len(m[1]); indent == 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 2
+	}
+	return indent
+}
+
+func (d *Document) top() *yaml.Node {
+	return d.root.Content[0]
+}
+
+/*
+AddPackage appends pkg to the file's `packages:` list, creating the list if
+needed. Like lint, it rejects names already listed in the file.
+*/
+func (d *Document) AddPackage(pkg Package) error {
+	if len(pkg.Name) == 0 {
+		return fmt.Errorf("package name is required")
+	}
+	item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(pkg.Name) == 1 {
+		addPair(item, "name", strNode(pkg.Name[0]))
+	} else {
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, n := range pkg.Name {
+			seq.Content = append(seq.Content, strNode(n))
+		}
+		addPair(item, "name", seq)
+	}
+	if pkg.Manager != "" {
+		addPair(item, "manager", strNode(pkg.Manager))
+	}
+	if pkg.Version != "" {
+		addPair(item, "version", strNode(pkg.Version))
+	}
+	if pkg.Optional {
+		addPair(item, "optional", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	}
+	if len(pkg.Groups) > 0 {
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, g := range pkg.Groups {
+			seq.Content = append(seq.Content, strNode(g))
+		}
+		addPair(item, "groups", seq)
+	}
+
+	pkgs := mappingValue(d.top(), "packages")
+	if pkgs == nil {
+		pkgs = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		addPair(d.top(), "packages", pkgs)
+	} else if pkgs.Kind == yaml.ScalarNode && pkgs.Tag == "!!null" {
+		*pkgs = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: pkgs.HeadComment, LineComment: pkgs.LineComment}
+	} else if pkgs.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s: packages is not a list", d.Path)
+	}
+	if pkgs.Style == yaml.FlowStyle && len(pkgs.Content) == 0 {
+		pkgs.Style = 0
+	}
+	pkgs.Content = append(pkgs.Content, item)
+
+	if diags := d.Validate(); len(diags) > 0 {
+		pkgs.Content = pkgs.Content[:len(pkgs.Content)-1]
+		return diags
+	}
+	return nil
+}
+
+// keySegment matches `name` or `name[3]` in a Set key.
+var (
+	keySegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+	keyIndex   = regexp.MustCompile(`\d+`)
+)
+
+// splitKey splits "packages[0].version" into ["packages", "0", "version"].
+func splitKey(key string) ([]string, error) {
+	var parts []string
+	for _, seg := range strings.Split(key, ".") {
+		m := keySegment.FindStringSubmatch(seg)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		if m[1] != "" {
+			parts = append(parts, m[1])
+		}
+		for _, idx := range keyIndex.FindAllString(m[2], -1) {
+			parts = append(parts, idx)
+		}
+	}
+	return parts, nil
+}
+
+/*
+Set assigns value to the dotted key, e.g. `commands.test.command` or
+`packages.node.version`. List items are addressed by index (`packages[0]` or
+`packages.0`) or, for packages, by name. Missing mapping keys are created.
+String fields take value literally; other fields parse it as YAML, so
+`optional true` or `name "[node, pnpm]"` work. Comments on the replaced
+node are kept.
+*/
+func (d *Document) Set(key, value string) (err error) {
+	parts, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	// Keys created on the way are dropped again if the edit is rejected
+	saved := cloneNode(&d.root)
+	defer func() {
+		if err != nil {
+			d.root = *saved
+		}
+	}()
+	node := d.top()
+	t := reflect.TypeOf(PackageConfig{})
+	for i, part := range parts {
+		last := i == len(parts)-1
+		ft, err := childType(t, part)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		var child *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			child = mappingValue(node, part)
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				addPair(node, part, child)
+			}
+		case yaml.SequenceNode:
+			child = sequenceItem(node, part)
+			if child == nil {
+				return fmt.Errorf("%s: no list item %q", key, part)
+			}
+		default:
+			return fmt.Errorf("%s: %q is not a mapping or list", key, strings.Join(parts[:i], "."))
+		}
+		if last {
+			return d.assign(child, ft, value)
+		}
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			child.Kind, child.Tag = yaml.MappingNode, "!!map"
+		}
+		node, t = child, ft
+	}
+	return nil
+}
+
+// assign replaces n in place with value decoded for type t, then validates.
+func (d *Document) assign(n *yaml.Node, t reflect.Type, value string) error {
+	var repl yaml.Node
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String && !reflect.PointerTo(t).Implements(unmarshalerType) {
+		repl = *strNode(value)
+	} else {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+			repl = *strNode(value)
+		} else {
+			repl = *doc.Content[0]
+		}
+	}
+	n.Kind, n.Tag, n.Value, n.Style, n.Content = repl.Kind, repl.Tag, repl.Value, repl.Style, repl.Content
+	if diags := d.Validate(); len(diags) > 0 {
+		return diags
+	}
+	return nil
+}
+
+func cloneNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = cloneNode(child)
+	}
+	return &c
+}
+
+// childType returns the Go type of part within t, or an error for unknown fields.
+func childType(t reflect.Type, part string) (reflect.Type, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		names, fields := yamlFields(t)
+		ft, ok := fields[part]
+		if !ok {
+			msg := fmt.Sprintf("unknown field %q in %s", part, kindNames[t])
+			if s := suggest(part, names); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return ft, nil
+	case reflect.Map, reflect.Slice:
+		return t.Elem(), nil
+	}
+	return nil, fmt.Errorf("cannot set %q inside a scalar value", part)
+}
+
+// sequenceItem finds a list item by index or, for mappings, by `name`.
+func sequenceItem(seq *yaml.Node, part string) *yaml.Node {
+	if idx, err := strconv.Atoi(part); err == nil {
+		if idx >= 0 && idx < len(seq.Content) {
+			return seq.Content[idx]
+		}
+		return nil
+	}
+	for _, item := range seq.Content {
+		name := mappingValue(item, "name")
+		if name == nil {
+			continue
+		}
+		if name.Kind == yaml.ScalarNode && name.Value == part {
+			return item
+		}
+		for _, n := range name.Content {
+			if n.Value == part {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+func strNode(s string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	if strings.Contains(s, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	return n
+}
+
+func addPair(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// Validate runs the strict per-file checks LoadConfig and lint apply, and
+// rejects commands that would override core commands.
+func (d *Document) Validate() Diagnostics {
+	diags := checkNode(d.Path, d.top(), reflect.TypeOf(PackageConfig{}))
+	if len(diags) > 0 {
+		return diags
+	}
+	var cfg PackageConfig
+	if err := d.top().Decode(&cfg); err != nil {
+		return yamlErrorDiagnostics(d.Path, err)
+	}
+	diags = checkPackages(d.Path, d.top(), &cfg)
+	if _, err := ValidateCommands(cfg.Commands); err != nil {
+		diags = append(diags, errorAt(d.Path, mappingValue(d.top(), "commands"), "%v", err))
+	}
+	return diags
+}
+
+// Bytes encodes the document with its original indentation width.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(d.indent)
+	if err := enc.Encode(&d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the document back to its path, replacing the file atomically.
+func (d *Document) Save() error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(d.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.Path), ".voltig-*.yml")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.Path)
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editTestConfig = `# Project tools
+packages:
+    # JSON processor
+    - name: jq
+      manager: brew # keep me
+    - name: [node, nodejs]
+      manager: brew
+      version: "20"
+
+commands:
+    test:
+        summary: Run tests
+        command: go test ./...
+`
+
+func openTestDocument(t *testing.T) (*Document, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	writeFile(t, path, editTestConfig)
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument failed: %v", err)
+	}
+	return doc, path
+}
+
+func TestDocumentAddPackage(t *testing.T) {
+	doc, path := openTestDocument(t)
+	if err := doc.AddPackage(Package{Name: PackageName{"ripgrep"}, Manager: "brew", Groups: StringList{"dev"}}); err != nil {
+		t.Fatalf("AddPackage failed: %v", err)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	for _, want := range []string{"# Project tools", "# JSON processor", "# keep me", "    - name: ripgrep\n      manager: brew\n      groups: [dev]\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Packages) != 3 || cfg.Packages[2].Name[0] != "ripgrep" {
+		t.Errorf("expected ripgrep appended, got %+v", cfg.Packages)
+	}
+}
+
+func TestDocumentAddPackageDuplicate(t *testing.T) {
+	doc, _ := openTestDocument(t)
+	before, _ := doc.Bytes()
+	if err := doc.AddPackage(Package{Name: PackageName{"nodejs"}, Manager: "brew"}); err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+	after, _ := doc.Bytes()
+	if string(before) != string(after) {
+		t.Errorf("expected document unchanged after rejected add")
+	}
+}
+
+func TestDocumentAddPackageNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument failed: %v", err)
+	}
+	if err := doc.AddPackage(Package{Name: PackageName{"jq"}, Manager: "brew"}); err != nil {
+		t.Fatalf("AddPackage failed: %v", err)
+	}
+	out, _ := doc.Bytes()
+	if string(out) != "packages:\n  - name: jq\n    manager: brew\n" {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc, _ := openTestDocument(t)
+	cases := []struct{ key, value, want string }{
+		{"packages.node.version", "22", `version: "22"`},
+		{"packages[0].optional", "true", "optional: true"},
+		{"commands.test.command", "go test -race ./...", "command: go test -race ./..."},
+		{"commands.check.command", "golangci-lint run", "    check:\n        command: golangci-lint run"},
+	}
+	for _, c := range cases {
+		if err := doc.Set(c.key, c.value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", c.key, err)
+		}
+		out, _ := doc.Bytes()
+		if !strings.Contains(string(out), c.want) {
+			t.Errorf("Set(%q): expected %q in:\n%s", c.key, c.want, out)
+		}
+	}
+	out, _ := doc.Bytes()
+	if !strings.Contains(string(out), "# keep me") {
+		t.Errorf("expected comments preserved, got:\n%s", out)
+	}
+}
+
+func TestDocumentSetErrors(t *testing.T) {
+	doc, _ := openTestDocument(t)
+	before, _ := doc.Bytes()
+	cases := []struct{ key, value, want string }{
+		{"packages.jq.manger", "brew", `did you mean "manager"`},
+		{"packages.missing.version", "1", "no list item"},
+		{"packages.jq.optional", "maybe", "expected true or false"},
+		{"packages[", "x", "invalid key"},
+		{"commands.install.command", "echo pwned", "protected by core CLI"},
+	}
+	for _, c := range cases {
+		err := doc.Set(c.key, c.value)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Set(%q): expected error containing %q, got %v", c.key, c.want, err)
+		}
+	}
+	after, _ := doc.Bytes()
+	if string(before) != string(after) {
+		t.Errorf("expected document unchanged after rejected sets:\n%s", after)
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+UnresolvedRef is a `${NAME}` reference without a value or default. The
+reference is left in place so the shell can still expand it at run time.
+*/
+type UnresolvedRef struct {
+	Field string // e.g. commands.build.command
+	Name  string
+}
+
+// refPattern matches an escaped `$${...}` or a `${NAME}` / `${NAME:-default}` reference.
+// Bare `$NAME` is deliberately not expanded so shell variables in commands keep working.
+var refPattern = regexp.MustCompile(`\$\$\{[^}]*\}|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandString replaces references in s using lookup and returns the names it
+// could not resolve.
+func expandString(s string, lookup func(string) (string, bool)) (string, []string) {
+	var missing []string
+	out := refPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+		sub := refPattern.FindStringSubmatch(m)
+		v, ok := lookup(sub[1])
+		if ok && (v != "" || sub[2] == "") {
+			return v
+		}
+		if sub[2] != "" {
+			return sub[3]
+		}
+		missing = append(missing, sub[1])
+		return m
+	})
+	return out, missing
+}
+
+/*
+LoadEnvFile parses a dotenv file: KEY=VALUE lines, optionally prefixed with
+`export`, with `#` comments and single- or double-quoted values.
+*/
+func LoadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %w", path, lineNo, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+/*
+interpolate loads the env files and expands `${...}` references in command
+fields and package versions. Lookups check, in order: `vars:`, the process
+environment, then `env_file:` values. Vars may reference each other.
+Unresolved references are recorded in c.Unresolved.
+*/
+func (c *PackageConfig) interpolate() error {
+	c.Env = make(map[string]string)
+	for _, path := range c.EnvFile {
+		env, err := LoadEnvFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Env files are usually untracked; lint reports missing ones.
+				continue
+			}
+			return err
+		}
+		for k, v := range env {
+			c.Env[k] = v
+		}
+	}
+
+	resolved := make(map[string]string)
+	visiting := make(map[string]bool)
+	c.Unresolved = nil
+	var lookup func(string) (string, bool)
+	lookup = func(name string) (string, bool) {
+		if v, ok := resolved[name]; ok {
+			return v, true
+		}
+		if raw, ok := c.Vars[name]; ok && !visiting[name] {
+			visiting[name] = true
+			v, missing := expandString(raw, lookup)
+			visiting[name] = false
+			for _, m := range missing {
+				c.Unresolved = append(c.Unresolved, UnresolvedRef{Field: "vars." + name, Name: m})
+			}
+			resolved[name] = v
+			return v, true
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := c.Env[name]
+		return v, ok
+	}
+	expand := func(field, s string) string {
+		out, missing := expandString(s, lookup)
+		for _, m := range missing {
+			c.Unresolved = append(c.Unresolved, UnresolvedRef{Field: field, Name: m})
+		}
+		return out
+	}
+
+	for _, name := range sortedNames(c.Vars) {
+		lookup(name)
+	}
+	for i := range c.Packages {
+		pkg := &c.Packages[i]
+		field := fmt.Sprintf("packages[%d].version", i)
+		if len(pkg.Name) > 0 {
+			field = "packages." + pkg.Name[0] + ".version"
+		}
+		pkg.Version = expand(field, pkg.Version)
+		prefix := strings.TrimSuffix(field, ".version")
+		for _, hooks := range []struct {
+			name  string
+			lines StringList
+		}{{"pre_install", pkg.PreInstall}, {"post_install", pkg.PostInstall}, {"pre_remove", pkg.PreRemove}, {"post_remove", pkg.PostRemove}} {
+			for j, line := range hooks.lines {
+				hooks.lines[j] = expand(prefix+"."+hooks.name, line)
+			}
+		}
+	}
+	c.Commands = expandCommands("commands", c.Commands, expand)
+	return nil
+}
+
+// expandCommands returns a copy of cmds with all runnable fields expanded.
+func expandCommands(prefix string, cmds map[string]CustomCommand, expand func(field, s string) string) map[string]CustomCommand {
+	if cmds == nil {
+		return nil
+	}
+	out := make(map[string]CustomCommand, len(cmds))
+	for name, cmd := range cmds {
+		field := prefix + "." + name
+		cmd.Command = expand(field+".command", cmd.Command)
+		cmd.Script = expand(field+".script", cmd.Script)
+		cmd.WorkDir = expand(field+".workDir", cmd.WorkDir)
+		args := make([]string, len(cmd.Args))
+		for i, a := range cmd.Args {
+			args[i] = expand(fmt.Sprintf("%s.args[%d]", field, i), a)
+		}
+		if cmd.Args != nil {
+			cmd.Args = args
+		}
+		env := make([]string, len(cmd.Environment))
+		for i, e := range cmd.Environment {
+			env[i] = expand(fmt.Sprintf("%s.environment[%d]", field, i), e)
+		}
+		if cmd.Environment != nil {
+			cmd.Environment = env
+		}
+		cmd.Commands = expandCommands(field+".commands", cmd.Commands, expand)
+		out[name] = cmd
+	}
+	return out
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandString(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		switch name {
+		case "NAME":
+			return "voltig", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+	tests := []struct {
+		in, want string
+		missing  int
+	}{
+		{"hello ${NAME}", "hello voltig", 0},
+		{"${MISSING:-fallback}", "fallback", 0},
+		{"${EMPTY:-fallback}", "fallback", 0},
+		{"${EMPTY}", "", 0},
+		{"keep $NAME and $$ and $(pwd)", "keep $NAME and $$ and $(pwd)", 0},
+		{"escaped $${NAME}", "escaped ${NAME}", 0},
+		{"${MISSING}/bin", "${MISSING}/bin", 1},
+	}
+	for _, tt := range tests {
+		got, missing := expandString(tt.in, lookup)
+		if got != tt.want || len(missing) != tt.missing {
+			t.Errorf("expandString(%q) = %q (missing %v), want %q (%d missing)", tt.in, got, missing, tt.want, tt.missing)
+		}
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	writeFile(t, path, `# comment
+export DB_USER=admin
+DB_PASSWORD="s3cr3t\nline"
+SINGLE='raw $value'
+PLAIN=value # trailing comment
+`)
+	env, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"DB_USER":     "admin",
+		"DB_PASSWORD": "s3cr3t\nline",
+		"SINGLE":      "raw $value",
+		"PLAIN":       "value",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s: got %q, want %q", k, env[k], v)
+		}
+	}
+	writeFile(t, path, "NOT A PAIR\n")
+	if _, err := LoadEnvFile(path); err == nil {
+		t.Errorf("expected error for malformed line")
+	}
+}
+
+func TestLoadConfigInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".env"), "DB_HOST=localhost\nNODE_VERSION=18\n")
+	path := filepath.Join(dir, "voltig.yml")
+	writeFile(t, path, `
+env_file: .env
+vars:
+  NODE_VERSION: "20"
+  BIN: ${OUT_DIR:-./bin}
+  TARGET: ${BIN}/voltig
+packages:
+  - name: node
+    manager: brew
+    version: ${NODE_VERSION}
+    post_install: corepack enable --install-directory ${BIN} $VOLTIG_VERSION
+commands:
+  build:
+    summary: Build
+    command: go build -o ${TARGET} .
+    args: ["--host=${DB_HOST}"]
+    environment: ["TARGET=${TARGET}"]
+    commands:
+      broken:
+        command: echo ${NOT_DEFINED_ANYWHERE_XYZ}
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Packages[0].Version != "20" {
+		t.Errorf("expected vars to take precedence over env_file, got %q", cfg.Packages[0].Version)
+	}
+	if hook := cfg.Packages[0].PostInstall; len(hook) != 1 || hook[0] != "corepack enable --install-directory ./bin $VOLTIG_VERSION" {
+		t.Errorf("unexpected post_install hook: %v", hook)
+	}
+	build := cfg.Commands["build"]
+	if build.Command != "go build -o ./bin/voltig ." {
+		t.Errorf("unexpected command: %q", build.Command)
+	}
+	if build.Args[0] != "--host=localhost" || build.Environment[0] != "TARGET=./bin/voltig" {
+		t.Errorf("unexpected args/environment: %v %v", build.Args, build.Environment)
+	}
+	if cfg.Env["DB_HOST"] != "localhost" {
+		t.Errorf("expected env_file values in Env, got %v", cfg.Env)
+	}
+	if len(cfg.Unresolved) != 1 || cfg.Unresolved[0].Field != "commands.build.commands.broken.command" {
+		t.Errorf("unexpected unresolved refs: %+v", cfg.Unresolved)
+	}
+}
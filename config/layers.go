@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+Sources records which file each config entry was loaded from.
+Packages are keyed by each of their names, commands and profiles by key.
+*/
+type Sources struct {
+	Files          []string // every loaded file in merge order
+	Packages       map[string]string
+	Commands       map[string]string
+	Profiles       map[string]string
+	Vars           map[string]string
+	DefaultProfile string
+	// Positions holds the location of each entry, see PositionOf.
+	Positions map[string]Position
+	// Inherited holds the package settings filled in from top-level
+	// defaults, keyed like "packages.<name>.retries".
+	Inherited map[string]bool
+}
+
+func newSources() *Sources {
+	return &Sources{
+		Packages:  make(map[string]string),
+		Commands:  make(map[string]string),
+		Profiles:  make(map[string]string),
+		Vars:      make(map[string]string),
+		Positions: make(map[string]Position),
+		Inherited: make(map[string]bool),
+	}
+}
+
+// userConfigPath returns the path of the user-level config. It is a variable
+// so tests can point it elsewhere.
+var userConfigPath = func() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "voltig", "config.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "voltig", "config.yml")
+}
+
+/*
+LocalConfigPath returns the untracked override file for a project config,
+e.g. voltig.local.yml for voltig.yml.
+*/
+func LocalConfigPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+// loader accumulates the merged config and diagnostics of all layers.
+type loader struct {
+	cfg   *PackageConfig
+	diags Diagnostics
+}
+
+/*
+loadLayer decodes path and merges it into the config. Files listed under
+`include:` are merged first, so the including file overrides what it
+includes. Include paths are relative to the including file and may be globs.
+stack holds the chain of files being loaded to detect include cycles.
+*/
+func (l *loader) loadLayer(path string, stack []string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	src, diags := decodeFile(path)
+	l.diags = append(l.diags, diags...)
+	if src == nil {
+		return
+	}
+	stack = append(stack, abs)
+	includePos := src.positions["include"]
+	includeErr := func(format string, args ...interface{}) {
+		l.diags = append(l.diags, Diagnostic{Position: includePos, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, pattern := range src.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			includeErr("invalid include %q: %v", pattern, err)
+			continue
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			includeErr("included file %q not found", pattern)
+			continue
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			matchAbs, _ := filepath.Abs(match)
+			if cycle := includeCycle(stack, matchAbs); cycle != "" {
+				includeErr("include cycle: %s", cycle)
+				continue
+			}
+			l.loadLayer(match, stack)
+		}
+	}
+	mergeConfig(l.cfg, src, path)
+}
+
+// includeCycle returns the cycle description if path is already on stack.
+func includeCycle(stack []string, path string) string {
+	for _, s := range stack {
+		if s == path {
+			return strings.Join(append(append([]string{}, stack...), path), " -> ")
+		}
+	}
+	return ""
+}
+
+/*
+mergeConfig merges src (loaded from file) into dst:
+  - a package replaces the existing package sharing any of its names, keeping
+    that package's position; new packages are appended
+  - commands and profiles replace existing entries with the same key
+  - vars replace existing vars with the same name
+  - env_file entries are appended, resolved relative to file
+  - default_profile, retries and retry_backoff are overridden when set
+*/
+func mergeConfig(dst, src *PackageConfig, file string) {
+	if dst.Sources == nil {
+		dst.Sources = newSources()
+	}
+	dst.Sources.Files = append(dst.Sources.Files, file)
+	for k, pos := range src.positions {
+		dst.Sources.Positions[k] = pos
+	}
+
+	for _, pkg := range src.Packages {
+		idx := -1
+		for i, existing := range dst.Packages {
+			if sharesName(existing, pkg) {
+				idx = i
+				break
+			}
+		}
+		if idx >= 0 {
+			for _, name := range dst.Packages[idx].Name {
+				delete(dst.Sources.Packages, name)
+			}
+			dst.Packages[idx] = pkg
+		} else {
+			dst.Packages = append(dst.Packages, pkg)
+		}
+		for _, name := range pkg.Name {
+			dst.Sources.Packages[name] = file
+		}
+	}
+	for name, c := range src.Commands {
+		if dst.Commands == nil {
+			dst.Commands = make(map[string]CustomCommand)
+		}
+		dst.Commands[name] = c
+		dst.Sources.Commands[name] = file
+	}
+	for name, p := range src.Profiles {
+		if dst.Profiles == nil {
+			dst.Profiles = make(map[string]Profile)
+		}
+		dst.Profiles[name] = p
+		dst.Sources.Profiles[name] = file
+	}
+	for name, v := range src.Vars {
+		if dst.Vars == nil {
+			dst.Vars = make(map[string]string)
+		}
+		dst.Vars[name] = v
+		dst.Sources.Vars[name] = file
+	}
+	for _, envFile := range src.EnvFile {
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(filepath.Dir(file), envFile)
+		}
+		dst.EnvFile = append(dst.EnvFile, envFile)
+	}
+	if src.DefaultProfile != "" {
+		dst.DefaultProfile = src.DefaultProfile
+		dst.Sources.DefaultProfile = file
+	}
+	if src.Retries != 0 {
+		dst.Retries = src.Retries
+	}
+	if src.RetryBackoff != "" {
+		dst.RetryBackoff = src.RetryBackoff
+	}
+}
+
+func sharesName(a, b Package) bool {
+	for _, x := range a.Name {
+		for _, y := range b.Name {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
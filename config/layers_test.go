@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Keep tests independent of the developer's ~/.config/voltig/config.yml
+	userConfigPath = func() string { return "" }
+	os.Exit(m.Run())
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	userFile := filepath.Join(dir, "home", "config.yml")
+	writeFile(t, userFile, `
+packages:
+  - name: jq
+    manager: brew
+  - name: node
+    manager: brew
+    version: "18"
+commands:
+  hello:
+    summary: from user
+    command: echo user
+`)
+	writeFile(t, filepath.Join(dir, "project", "shared", "a.yml"), `
+packages:
+  - name: go
+    manager: brew
+commands:
+  build:
+    summary: shared build
+    command: make
+`)
+	writeFile(t, filepath.Join(dir, "project", "shared", "b.yml"), `
+packages:
+  - name: ["node", "pnpm"]
+    manager: brew
+    version: "20"
+`)
+	project := filepath.Join(dir, "project", "voltig.yml")
+	writeFile(t, project, `
+include:
+  - shared/*.yml
+packages:
+  - name: ripgrep
+    manager: brew
+commands:
+  build:
+    summary: project build
+    command: go build
+`)
+	writeFile(t, filepath.Join(dir, "project", "voltig.local.yml"), `
+packages:
+  - name: go
+    manager: brew
+    version: "1.22"
+default_profile: all
+`)
+
+	orig := userConfigPath
+	userConfigPath = func() string { return userFile }
+	defer func() { userConfigPath = orig }()
+
+	cfg, err := LoadConfig(project)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	var got []string
+	for _, pkg := range cfg.Packages {
+		got = append(got, strings.Join(pkg.Name, "+")+"@"+pkg.Version)
+	}
+	want := "jq@,node+pnpm@20,go@1.22,ripgrep@"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got packages %v, want %s", got, want)
+	}
+	if cfg.Commands["build"].Summary != "project build" || cfg.Commands["hello"].Summary != "from user" {
+		t.Errorf("unexpected commands: %+v", cfg.Commands)
+	}
+	if cfg.DefaultProfile != "all" {
+		t.Errorf("expected default_profile from local override, got %q", cfg.DefaultProfile)
+	}
+	if len(cfg.Sources.Files) != 5 {
+		t.Errorf("expected 5 loaded files, got %v", cfg.Sources.Files)
+	}
+	if src := cfg.Sources.Packages["go"]; !strings.HasSuffix(src, "voltig.local.yml") {
+		t.Errorf("expected go from local override, got %s", src)
+	}
+	if src := cfg.Sources.Packages["node"]; !strings.HasSuffix(src, "b.yml") {
+		t.Errorf("expected node from shared/b.yml, got %s", src)
+	}
+	if src := cfg.Sources.Commands["hello"]; src != userFile {
+		t.Errorf("expected hello from user config, got %s", src)
+	}
+	if cfg.Path != project {
+		t.Errorf("expected Path %s, got %s", project, cfg.Path)
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yml"), "include: b.yml\n")
+	writeFile(t, filepath.Join(dir, "b.yml"), "include: a.yml\n")
+	if _, err := LoadConfig(filepath.Join(dir, "a.yml")); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected include cycle error, got %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "c.yml"), "include: missing.yml\n")
+	if _, err := LoadConfig(filepath.Join(dir, "c.yml")); err == nil {
+		t.Errorf("expected missing include error")
+	}
+	writeFile(t, filepath.Join(dir, "d.yml"), "include: none/*.yml\n")
+	if _, err := LoadConfig(filepath.Join(dir, "d.yml")); err != nil {
+		t.Errorf("expected empty glob to be allowed, got %v", err)
+	}
+}
+
+func TestLocalConfigPath(t *testing.T) {
+	if got := LocalConfigPath("/a/voltig.yml"); got != "/a/voltig.local.yml" {
+		t.Errorf("unexpected local path %s", got)
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AllProfile is the built-in profile that selects every package.
+const AllProfile = "all"
+
+/*
+Profile selects a subset of packages. A package belongs to a profile if it is
+listed by name, tagged with the profile (or one of its Groups) via the
+package's `groups:` field, or belongs to a profile this one extends.
+*/
+type Profile struct {
+	Summary  string     `yaml:"summary,omitempty"`
+	Extends  StringList `yaml:"extends,omitempty"`
+	Packages []string   `yaml:"packages,omitempty"`
+	Groups   StringList `yaml:"groups,omitempty"`
+}
+
+/*
+ProfileNames returns the names of all profiles, both those declared under
+`profiles:` and those only referenced by a package's `groups:`, sorted.
+*/
+func (c *PackageConfig) ProfileNames() []string {
+	set := make(map[string]struct{})
+	for name := range c.Profiles {
+		set[name] = struct{}{}
+	}
+	for _, pkg := range c.Packages {
+		for _, g := range pkg.Groups {
+			set[g] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+ProfilePackages returns the packages of the named profile in config order.
+*/
+func (c *PackageConfig) ProfilePackages(name string) ([]Package, error) {
+	if name == AllProfile {
+		if _, defined := c.Profiles[name]; !defined {
+			return c.Packages, nil
+		}
+	}
+	selected := make([]bool, len(c.Packages))
+	if err := c.collectProfile(name, selected, nil); err != nil {
+		return nil, err
+	}
+	var pkgs []Package
+	for i, pkg := range c.Packages {
+		if selected[i] {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+// collectProfile marks the packages of name (and the profiles it extends) in
+// selected. stack holds the chain of profiles being resolved to detect cycles.
+func (c *PackageConfig) collectProfile(name string, selected []bool, stack []string) error {
+	for _, s := range stack {
+		if s == name {
+			return fmt.Errorf("profile inheritance cycle: %s", strings.Join(append(stack, name), " -> "))
+		}
+	}
+	profile, defined := c.Profiles[name]
+	if !defined && !c.hasGroup(name) {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	stack = append(stack, name)
+
+	groups := append([]string{name}, profile.Groups...)
+	for i, pkg := range c.Packages {
+		for _, g := range pkg.Groups {
+			for _, want := range groups {
+				if g == want {
+					selected[i] = true
+				}
+			}
+		}
+	}
+	for _, pkgName := range profile.Packages {
+		found := false
+		for i, pkg := range c.Packages {
+			for _, n := range pkg.Name {
+				if n == pkgName {
+					selected[i] = true
+					found = true
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("profile %q references unknown package %q", name, pkgName)
+		}
+	}
+	for _, parent := range profile.Extends {
+		if parent == AllProfile {
+			if _, defined := c.Profiles[parent]; !defined {
+				for i := range selected {
+					selected[i] = true
+				}
+				continue
+			}
+		}
+		if err := c.collectProfile(parent, selected, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PackageConfig) hasGroup(name string) bool {
+	for _, pkg := range c.Packages {
+		for _, g := range pkg.Groups {
+			if g == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+ValidateProfiles resolves every profile and the default profile, reporting
+inheritance cycles, unknown profiles and unknown package references. The
+returned error is a Diagnostics listing every problem, or nil.
+*/
+func (c *PackageConfig) ValidateProfiles() error {
+	var diags Diagnostics
+	for _, name := range c.ProfileNames() {
+		if _, err := c.ProfilePackages(name); err != nil {
+			diags = append(diags, Diagnostic{Position: c.PositionOf("profiles." + name), Severity: SeverityError, Message: err.Error()})
+		}
+	}
+	if c.DefaultProfile != "" {
+		if _, err := c.ProfilePackages(c.DefaultProfile); err != nil {
+			diags = append(diags, Diagnostic{Position: c.PositionOf("default_profile"), Severity: SeverityError, Message: "default_profile: " + err.Error()})
+		}
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+	return diags
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func profileTestConfig() *PackageConfig {
+	return &PackageConfig{
+		Packages: []Package{
+			{Name: []string{"go"}, Groups: []string{"backend"}},
+			{Name: []string{"postgresql"}, Groups: []string{"backend", "db"}},
+			{Name: []string{"node", "pnpm"}},
+			{Name: []string{"jq"}},
+		},
+		Profiles: map[string]Profile{
+			"frontend": {Packages: []string{"pnpm"}},
+			"full":     {Extends: []string{"backend", "frontend"}, Packages: []string{"jq"}},
+			"data":     {Groups: []string{"db"}},
+		},
+		DefaultProfile: "backend",
+	}
+}
+
+func names(pkgs []Package) string {
+	var out []string
+	for _, p := range pkgs {
+		out = append(out, p.Name[0])
+	}
+	return strings.Join(out, ",")
+}
+
+func TestProfilePackages(t *testing.T) {
+	cfg := profileTestConfig()
+	tests := map[string]string{
+		"backend":  "go,postgresql",
+		"frontend": "node",
+		"full":     "go,postgresql,node,jq",
+		"data":     "postgresql",
+		"db":       "postgresql",
+		AllProfile: "go,postgresql,node,jq",
+	}
+	for profile, want := range tests {
+		pkgs, err := cfg.ProfilePackages(profile)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", profile, err)
+			continue
+		}
+		if got := names(pkgs); got != want {
+			t.Errorf("%s: got %s, want %s", profile, got, want)
+		}
+	}
+	if _, err := cfg.ProfilePackages("nope"); err == nil {
+		t.Errorf("expected error for unknown profile")
+	}
+}
+
+func TestProfileNames(t *testing.T) {
+	got := strings.Join(profileTestConfig().ProfileNames(), ",")
+	if got != "backend,data,db,frontend,full" {
+		t.Errorf("unexpected profile names: %s", got)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	if err := profileTestConfig().ValidateProfiles(); err != nil {
+		t.Errorf("expected valid profiles, got %v", err)
+	}
+
+	cyclic := profileTestConfig()
+	cyclic.Profiles["a"] = Profile{Extends: []string{"b"}}
+	cyclic.Profiles["b"] = Profile{Extends: []string{"a"}}
+	if err := cyclic.ValidateProfiles(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+
+	unknownPkg := profileTestConfig()
+	unknownPkg.Profiles["x"] = Profile{Packages: []string{"missing"}}
+	if err := unknownPkg.ValidateProfiles(); err == nil {
+		t.Errorf("expected unknown package error")
+	}
+
+	badDefault := profileTestConfig()
+	badDefault.DefaultProfile = "missing"
+	if err := badDefault.ValidateProfiles(); err == nil {
+		t.Errorf("expected unknown default profile error")
+	}
+}
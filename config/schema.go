@@ -0,0 +1,84 @@
+package config
+
+import (
+	"reflect"
+)
+
+// requiredFields lists the YAML keys the schema marks as required per type.
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(Package{}): {"name", "manager"},
+}
+
+/*
+JSONSchema returns a JSON Schema (draft 2020-12) describing voltig.yml, derived
+from PackageConfig so it stays in sync with what LoadConfig accepts.
+Editors can use it to validate and autocomplete config files.
+*/
+func JSONSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	root := schemaFor(reflect.TypeOf(PackageConfig{}), defs)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "voltig.yml"
+	root["$defs"] = defs
+	return root
+}
+
+// schemaFor returns the schema for t, registering named struct types in defs.
+func schemaFor(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		// PackageName and StringList accept a string or a list of strings
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		names, fields := yamlFields(t)
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+		}
+		if t != reflect.TypeOf(PackageConfig{}) {
+			// Register the definition before recursing so recursive types terminate
+			if _, seen := defs[t.Name()]; !seen {
+				defs[t.Name()] = schema
+				for _, name := range names {
+					properties[name] = schemaFor(fields[name], defs)
+				}
+				schema["properties"] = properties
+				if req, ok := requiredFields[t]; ok {
+					schema["required"] = req
+				}
+			}
+			return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		}
+		for _, name := range names {
+			properties[name] = schemaFor(fields[name], defs)
+		}
+		schema["properties"] = properties
+		return schema
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem(), defs),
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), defs),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
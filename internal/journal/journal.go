@@ -0,0 +1,164 @@
+/*
+Package journal keeps an append-only record of the package operations voltig
+ran on this machine, for `voltig history` and `voltig undo`. Entries are JSON
+lines in journal.jsonl next to the state file.
+*/
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+	"voltig/internal/state"
+)
+
+// FileName is the name of the journal in the voltig state directory.
+const FileName = "journal.jsonl"
+
+// Outcomes of a recorded operation.
+const (
+	StatusOK        = "ok"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// DefaultPath returns the journal location, next to the state file.
+func DefaultPath() string {
+	p := state.DefaultPath()
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(p), FileName)
+}
+
+// Entry is one operation on one package.
+type Entry struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Op      string    `json:"op"` // install, update or remove
+	Package string    `json:"package"`
+	Manager string    `json:"manager"`
+	Before  string    `json:"before,omitempty"` // installed version before, empty if missing
+	After   string    `json:"after,omitempty"`  // installed version after, empty if missing
+	Status  string    `json:"status"`
+	Undoes  int       `json:"undoes,omitempty"` // ID of the entry this one reverses
+}
+
+// Journal is the journal file at Path.
+type Journal struct {
+	Path string
+}
+
+// Open returns the journal at path; the file is created on the first Record.
+func Open(path string) *Journal {
+	return &Journal{Path: path}
+}
+
+// Entries returns every recorded entry, oldest first. A missing journal has
+// no entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	f, err := os.Open(j.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", j.Path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Get returns the entry with id.
+func (j *Journal) Get(id int) (Entry, bool, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+/*
+Record appends entries, numbering them after the last recorded entry and
+filling in the time and current user where unset. The file is only ever
+appended to.
+*/
+func (j *Journal) Record(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	existing, err := j.Entries()
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].ID + 1
+	}
+	if err := os.MkdirAll(filepath.Dir(j.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	now := time.Now().UTC()
+	who := currentUser()
+	for _, e := range entries {
+		e.ID = next
+		next++
+		if e.Time.IsZero() {
+			e.Time = now
+		}
+		if e.User == "" {
+			e.User = who
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// UndoneBy returns the ID of the entry that reversed id, or 0.
+func UndoneBy(entries []Entry, id int) int {
+	for _, e := range entries {
+		if e.Undoes == id && e.Status == StatusOK {
+			return e.ID
+		}
+	}
+	return 0
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,57 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournalRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voltig", FileName)
+	j := Open(path)
+	if entries, err := j.Entries(); err != nil || len(entries) != 0 {
+		t.Fatalf("missing journal: entries=%v err=%v", entries, err)
+	}
+	if err := j.Record(
+		Entry{Op: "install", Package: "jq", Manager: "brew", After: "1.7.1", Status: StatusOK},
+		Entry{Op: "install", Package: "rg", Manager: "brew", Status: StatusFailed},
+	); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := j.Record(Entry{Op: "remove", Package: "jq", Manager: "brew", Before: "1.7.1", Status: StatusOK, Undoes: 1}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %v (%v)", entries, err)
+	}
+	for i, e := range entries {
+		if e.ID != i+1 || e.Time.IsZero() || e.User == "" {
+			t.Errorf("entry %d not numbered or stamped: %+v", i, e)
+		}
+	}
+	if e, ok, _ := j.Get(2); !ok || e.Package != "rg" {
+		t.Errorf("Get(2) = %+v, %v", e, ok)
+	}
+	if UndoneBy(entries, 1) != 3 || UndoneBy(entries, 2) != 0 {
+		t.Error("unexpected UndoneBy results")
+	}
+
+	// The file is only appended to, one JSON object per line
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Errorf("expected 3 lines, got %d", len(lines))
+	}
+}
+
+func TestJournalCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("{\"id\":1}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path).Entries(); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected an error naming line 2, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Package lock reads and writes voltig.lock, which records the exact version
+each package resolved to so every machine installs the same tools.
+*/
+package lock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"voltig/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the lockfile, written next to voltig.yml.
+const FileName = "voltig.lock"
+
+// formatVersion is bumped when the lockfile layout changes incompatibly.
+const formatVersion = 1
+
+const header = "# voltig.lock is generated by `voltig install` and `voltig lock`. Do not edit.\n"
+
+// Entry is the locked state of one package name. A package with several
+// names has an entry for each.
+type Entry struct {
+	Name       string `yaml:"name"`
+	Manager    string `yaml:"manager"`
+	Constraint string `yaml:"constraint,omitempty"` // version as written in voltig.yml
+	Version    string `yaml:"version"`
+	Source     string `yaml:"source,omitempty"`
+	Checksum   string `yaml:"checksum,omitempty"`
+}
+
+// File is the content of a voltig.lock.
+type File struct {
+	Path     string  `yaml:"-"`
+	Version  int     `yaml:"version"`
+	Packages []Entry `yaml:"packages"`
+}
+
+// PathFor returns the lockfile path belonging to the config file at configPath.
+func PathFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// Load reads the lockfile at path. A missing file yields an empty lock.
+func Load(path string) (*File, error) {
+	f := &File{Path: path, Version: formatVersion}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Version > formatVersion {
+		return nil, fmt.Errorf("%s: lockfile version %d is newer than this voltig supports (%d)", path, f.Version, formatVersion)
+	}
+	f.Path = path
+	return f, nil
+}
+
+// Exists reports whether the lockfile has been written before.
+func (f *File) Exists() bool {
+	_, err := os.Stat(f.Path)
+	return err == nil
+}
+
+// Get returns the entry for the package named name.
+func (f *File) Get(name string) (Entry, bool) {
+	for _, e := range f.Packages {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Set adds or replaces the entry with e's name.
+func (f *File) Set(e Entry) {
+	for i := range f.Packages {
+		if f.Packages[i].Name == e.Name {
+			f.Packages[i] = e
+			return
+		}
+	}
+	f.Packages = append(f.Packages, e)
+}
+
+// knownNames returns every name of pkgs.
+func knownNames(pkgs []config.Package) map[string]bool {
+	known := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, name := range pkg.Name {
+			known[name] = true
+		}
+	}
+	return known
+}
+
+// Prune drops entries for names no longer in pkgs and returns them.
+func (f *File) Prune(pkgs []config.Package) []string {
+	known := knownNames(pkgs)
+	var kept []Entry
+	var removed []string
+	for _, e := range f.Packages {
+		if known[e.Name] {
+			kept = append(kept, e)
+		} else {
+			removed = append(removed, e.Name)
+		}
+	}
+	f.Packages = kept
+	return removed
+}
+
+/*
+Verify checks that every name of the packages in targets has a lock entry with
+the same manager and version constraint as voltig.yml, and that the lock has
+no entries for names missing from all (the whole config). It returns one
+message per disagreement.
+*/
+func (f *File) Verify(targets, all []config.Package) []string {
+	var problems []string
+	for _, pkg := range targets {
+		for _, name := range pkg.Name {
+			e, ok := f.Get(name)
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s is not in %s", name, FileName))
+			case e.Manager != pkg.Manager:
+				problems = append(problems, fmt.Sprintf("%s: manager is %q in voltig.yml but %q in %s", name, pkg.Manager, e.Manager, FileName))
+			case e.Constraint != pkg.Version:
+				problems = append(problems, fmt.Sprintf("%s: version is %q in voltig.yml but was locked for %q", name, pkg.Version, e.Constraint))
+			}
+		}
+	}
+	known := knownNames(all)
+	for _, e := range f.Packages {
+		if !known[e.Name] {
+			problems = append(problems, fmt.Sprintf("%s is locked but no longer in voltig.yml", e.Name))
+		}
+	}
+	return problems
+}
+
+// Save writes the lockfile with entries sorted by name.
+func (f *File) Save() error {
+	sort.Slice(f.Packages, func(i, j int) bool {
+		return strings.ToLower(f.Packages[i].Name) < strings.ToLower(f.Packages[j].Name)
+	})
+	f.Version = formatVersion
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,84 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if f.Exists() || len(f.Packages) != 0 {
+		t.Fatalf("expected empty lock for missing file")
+	}
+	f.Set(Entry{Name: "node", Manager: "brew", Constraint: "20", Version: "20.11.1"})
+	f.Set(Entry{Name: "jq", Manager: "brew", Constraint: "latest", Version: "1.7", Source: "homebrew/core", Checksum: "sha256:abc"})
+	f.Set(Entry{Name: "jq", Manager: "brew", Constraint: "latest", Version: "1.7.1"})
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# voltig.lock") {
+		t.Errorf("expected header comment, got:\n%s", data)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(got.Packages) != 2 || got.Packages[0].Name != "jq" || got.Packages[0].Version != "1.7.1" {
+		t.Errorf("unexpected entries: %+v", got.Packages)
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("version: 99\npackages: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for unsupported lockfile version")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f := &File{Packages: []Entry{
+		{Name: "jq", Manager: "brew", Constraint: "latest", Version: "1.7.1"},
+		{Name: "node", Manager: "brew", Constraint: "18", Version: "18.19.0"},
+		{Name: "old", Manager: "brew", Version: "1.0"},
+	}}
+	pkgs := []config.Package{
+		{Name: config.PackageName{"jq"}, Manager: "brew", Version: "latest"},
+		{Name: config.PackageName{"node"}, Manager: "brew", Version: "20"},
+		{Name: config.PackageName{"go"}, Manager: "brew"},
+		{Name: config.PackageName{"jq", "gojq"}, Manager: "brew", Version: "latest"},
+	}
+	problems := f.Verify(pkgs, pkgs)
+	want := []string{"node: version", "go is not in", "gojq is not in", "old is locked"}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i], w) {
+			t.Errorf("problem %d: expected %q, got %q", i, w, problems[i])
+		}
+	}
+	if problems := f.Verify(pkgs[:1], pkgs[:2]); len(problems) != 1 {
+		t.Errorf("expected only the stale entry, got %v", problems)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	f := &File{Packages: []Entry{{Name: "jq"}, {Name: "old"}, {Name: "npm"}}}
+	removed := f.Prune([]config.Package{{Name: config.PackageName{"jq"}}, {Name: config.PackageName{"node", "npm"}}})
+	if len(removed) != 1 || removed[0] != "old" || len(f.Packages) != 2 {
+		t.Errorf("unexpected prune result: removed=%v kept=%+v", removed, f.Packages)
+	}
+}
@@ -3,6 +3,8 @@ package manager
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,27 +12,126 @@ import (
 	"strings"
 
 	"voltig/internal/models"
+	"voltig/internal/proc"
+	"voltig/internal/semver"
 	"voltig/pkg/logger"
 )
 
-// Allow stubbing exec.Command and exec.LookPath in tests
-var execCommand = exec.Command
+// Allow stubbing proc.Command and exec.LookPath in tests
+var execCommand = proc.Command
 var execLookPath = exec.LookPath
 var execOutput = func(cmd *exec.Cmd) ([]byte, error) { return cmd.Output() }
 
 // BrewManager provides Homebrew package management on macOS.
 type BrewManager struct{}
+
+// brewRemoveEnv keeps `brew uninstall` from updating Homebrew first.
+const brewRemoveEnv = "HOMEBREW_NO_AUTO_UPDATE=1"
+
+// brewArgs returns the brew arguments that perform op on name of pkg.
+func brewArgs(op, name string, pkg models.Package) []string {
+	switch op {
+	case OpInstall:
+		verb := "install"
+		if pkg.Reinstall {
+			verb = "reinstall"
+		}
+		if pkg.Version != "" && pkg.Version != "latest" {
+			// Older versions are separate versioned formulae, e.g. node@20
+			return []string{verb, fmt.Sprintf("%s@%s", name, pkg.Version)}
+		}
+		return []string{verb, name}
+	case OpUpdate:
+		return []string{"upgrade", name}
+	case OpRemove:
+		return []string{"uninstall", name}
+	}
+	return nil
+}
+
+// Commands returns the brew command lines op would run for pkg.
+func (b *BrewManager) Commands(op string, pkg models.Package) []string {
+	var lines []string
+	for _, name := range pkg.Name {
+		line := "brew " + shellJoin(brewArgs(op, name, pkg))
+		if op == OpRemove {
+			line = brewRemoveEnv + " " + line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// brewStderrLines is how many trailing stderr lines a brewError keeps.
+const brewStderrLines = 5
+
+// appendTail appends line to lines, keeping only the last brewStderrLines.
+func appendTail(lines []string, line string) []string {
+	lines = append(lines, line)
+	if len(lines) > brewStderrLines {
+		lines = lines[len(lines)-brewStderrLines:]
+	}
+	return lines
+}
+
+// brewError is a failed brew command with the end of its stderr, which tells
+// transient failures apart from permanent ones.
+type brewError struct {
+	err    error
+	stderr []string
+}
+
+func (e *brewError) Error() string {
+	if len(e.stderr) == 0 {
+		return e.err.Error()
+	}
+	return e.err.Error() + ": " + e.stderr[len(e.stderr)-1]
+}
+
+func (e *brewError) Unwrap() error { return e.err }
+
+// brewFailures maps stderr fragments to whether the failure is worth
+// retrying and why. Matching is case-insensitive; the first match wins.
+var brewFailures = []struct {
+	fragment string
+	retry    bool
+	reason   string
+}{
+	{"has already locked", true, "lock held"},
+	{"another active homebrew", true, "lock held"},
+	{"failed to download", true, "download failed"},
+	{"download failed", true, "download failed"},
+	{"curl: (", true, "download failed"},
+	{"could not resolve host", true, "network error"},
+	{"connection reset", true, "network error"},
+	{"operation timed out", true, "network error"},
+	{"no available formula", false, "no such package"},
+	{"no formulae or casks found", false, "no such package"},
+	{"no available cask", false, "no such package"},
+	{"no such keg", false, "not installed"},
+}
+
+// Retryable classifies the failure by its stderr; unrecognised failures are
+// retried.
+func (e *brewError) Retryable() (bool, string) {
+	for i := len(e.stderr) - 1; i >= 0; i-- {
+		line := strings.ToLower(e.stderr[i])
+		for _, f := range brewFailures {
+			if strings.Contains(line, f.fragment) {
+				return f.retry, f.reason
+			}
+		}
+	}
+	return true, "brew failed"
+}
+
 // Install package
-func (b *BrewManager) Install(pkg models.Package, outputFn func(string)) error {
+func (b *BrewManager) Install(ctx context.Context, pkg models.Package, outputFn func(string)) error {
 	// Handle multiple package names
 	for _, name := range pkg.Name {
 		logger.Info("Installing package", "name", name)
 		
-		args := []string{"install", name}
-		if pkg.Version != "" && pkg.Version != "latest" {
-			args = append(args, "--cask", fmt.Sprintf("%s@%s", name, pkg.Version))
-		}
-		cmd := execCommand("brew", args...)
+		cmd := execCommand(ctx, "brew", brewArgs(OpInstall, name, pkg)...)
 
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -45,6 +146,8 @@ func (b *BrewManager) Install(pkg models.Package, outputFn func(string)) error {
 			return err
 		}
 
+		// errLines is only read once both streams are done
+		var errLines []string
 		done := make(chan struct{}, 2)
 		// Stream stdout
 		go func() {
@@ -74,6 +177,7 @@ func (b *BrewManager) Install(pkg models.Package, outputFn func(string)) error {
 				for _, l := range lines {
 					trimmed := strings.TrimSpace(l)
 					if trimmed != "" {
+						errLines = appendTail(errLines, trimmed)
 						if outputFn != nil {
 							outputFn(trimmed)
 						} else {
@@ -90,7 +194,7 @@ func (b *BrewManager) Install(pkg models.Package, outputFn func(string)) error {
 		<-done
 
 		if err := cmd.Wait(); err != nil {
-			return fmt.Errorf("failed to install %s: %w", name, err)
+			return fmt.Errorf("failed to install %s: %w", name, &brewError{err: err, stderr: errLines})
 		}
 	}
 	
@@ -98,29 +202,42 @@ func (b *BrewManager) Install(pkg models.Package, outputFn func(string)) error {
 }
 
 // Update package
-func (b *BrewManager) Update(pkg models.Package) error {
+func (b *BrewManager) Update(ctx context.Context, pkg models.Package) error {
 	// Handle multiple package names
 	for _, name := range pkg.Name {
 		logger.Info("Updating package", "name", name)
 		
-		cmd := execCommand("brew", "upgrade", name)
+		if keg, _, ok := b.installedKeg(ctx, name, pkg.Version); ok {
+			name = keg
+		}
+		cmd := execCommand(ctx, "brew", brewArgs(OpUpdate, name, pkg)...)
+		var stderr strings.Builder
 		cmd.Stdout = nil
-		cmd.Stderr = nil
+		cmd.Stderr = &stderr
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to update %s: %w", name, err)
+			var errLines []string
+			for _, line := range strings.Split(stderr.String(), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					errLines = appendTail(errLines, line)
+				}
+			}
+			return fmt.Errorf("failed to update %s: %w", name, &brewError{err: err, stderr: errLines})
 		}
 	}
 	return nil
 }
 
 // Remove package
-func (b *BrewManager) Remove(pkg models.Package, outputFn func(string)) error {
+func (b *BrewManager) Remove(ctx context.Context, pkg models.Package, outputFn func(string)) error {
 	// Handle multiple package names
 	for _, name := range pkg.Name {
 		logger.Info("Removing package", "name", name)
 		
-		cmd := execCommand("brew", "uninstall", name)
-		cmd.Env = append(os.Environ(), "HOMEBREW_NO_AUTO_UPDATE=1")
+		if keg, _, ok := b.installedKeg(ctx, name, pkg.Version); ok {
+			name = keg
+		}
+		cmd := execCommand(ctx, "brew", brewArgs(OpRemove, name, pkg)...)
+		cmd.Env = append(os.Environ(), brewRemoveEnv)
 
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -134,6 +251,8 @@ func (b *BrewManager) Remove(pkg models.Package, outputFn func(string)) error {
 		if err := cmd.Start(); err != nil {
 			return err
 		}
+		// errLines is only read once both streams are done
+		var errLines []string
 		done := make(chan struct{}, 2)
 		// Stream stdout
 		go func() {
@@ -167,6 +286,7 @@ func (b *BrewManager) Remove(pkg models.Package, outputFn func(string)) error {
 				for _, l := range lines {
 					trimmed := strings.TrimSpace(l)
 					if trimmed != "" {
+						errLines = appendTail(errLines, trimmed)
 						// Only add prefix if line does not already start with [something]
 						if !regexp.MustCompile(`^\[[^\]]+\] `).MatchString(trimmed) {
 							trimmed = "[" + name + "] " + trimmed
@@ -184,63 +304,86 @@ func (b *BrewManager) Remove(pkg models.Package, outputFn func(string)) error {
 		<-done
 		<-done
 		if err := cmd.Wait(); err != nil {
-			return fmt.Errorf("failed to remove %s: %w", name, err)
+			return fmt.Errorf("failed to remove %s: %w", name, &brewError{err: err, stderr: errLines})
 		}
 	}
 	return nil
 }
 
-// GetStatus checks the status of a package
-func (b *BrewManager) GetStatus(pkg models.Package) (models.PackageStatus, error) {
-	// For packages with multiple names, we'll check the first one
+// GetStatus checks the status of each name of pkg
+func (b *BrewManager) GetStatus(ctx context.Context, pkg models.Package) ([]models.PackageStatus, error) {
 	if len(pkg.Name) == 0 {
-		return models.PackageStatus{}, fmt.Errorf("package has no name")
+		return nil, fmt.Errorf("package has no name")
 	}
-	
-	name := pkg.Name[0]
-	cmd := execCommand("brew", "list", "--versions", name)
-	out, err := execOutput(cmd)
-	if err == nil && len(out) > 0 {
-		return models.PackageStatus{Name: name, Status: "installed", Version: string(out)}, nil
+	statuses := make([]models.PackageStatus, 0, len(pkg.Name))
+	for _, name := range pkg.Name {
+		statuses = append(statuses, b.nameStatus(ctx, name, pkg.Version))
 	}
-	// Not managed by brew, check if binary exists in PATH
-	if path, lookErr := execLookPath(name); lookErr == nil && path != "" {
-		// Try to detect version manager
-		var manager, version string
-		if name == "node" || name == "nodejs" {
-			if nvmPath, _ := execLookPath("nvm"); nvmPath != "" || isNvmNode(path) {
-				manager = "nvm"
-			}
-		}
-		if name == "python" || name == "python3" {
-			if pyenvPath, _ := execLookPath("pyenv"); pyenvPath != "" || isPyenvPython(path) {
-				manager = "pyenv"
-			}
+	return statuses, nil
+}
+
+/*
+installedKeg returns the installed formula or cask providing name and its
+version: name itself or, when that is missing or doesn't satisfy constraint,
+the newest versioned formula such as node@20 that does. Versioned formulae are
+keg-only, so they are found through `brew list` rather than PATH.
+*/
+func (b *BrewManager) installedKeg(ctx context.Context, name, constraint string) (keg, version string, ok bool) {
+	// Output is "<name> <version>...", the newest version last
+	out, err := execOutput(execCommand(ctx, "brew", "list", "--versions", name))
+	if fields := strings.Fields(string(out)); err == nil && len(fields) > 1 {
+		keg, version, ok = name, fields[len(fields)-1], true
+	}
+	c, err := semver.ParseConstraint(constraint)
+	if err != nil || (ok && semver.Status(c, version) == semver.StatusOK) {
+		return keg, version, ok
+	}
+	out, err = execOutput(execCommand(ctx, "brew", "list", "--versions"))
+	if err != nil {
+		return keg, version, ok
+	}
+	var best semver.Version
+	found := false
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], name+"@") {
+			continue
 		}
-		// Try to get version
-		version = getBinaryVersion(name)
-		status := "installed (external)"
-		if manager != "" {
-			status = "installed (" + manager + ")"
+		v, err := semver.Parse(fields[len(fields)-1])
+		if err != nil || !c.Check(v) || (found && v.Compare(best) <= 0) {
+			continue
 		}
-		return models.PackageStatus{Name: name, Status: status, Version: version}, nil
+		best, found = v, true
+		keg, version, ok = fields[0], fields[len(fields)-1], true
 	}
-	return models.PackageStatus{Name: name, Status: "missing"}, nil
+	return keg, version, ok
 }
 
-// isNvmNode checks if the node binary is managed by nvm
-func isNvmNode(path string) bool {
-	return strings.Contains(path, ".nvm/versions/")
-}
-
-// isPyenvPython checks if the python binary is managed by pyenv
-func isPyenvPython(path string) bool {
-	return strings.Contains(path, ".pyenv/versions/")
+// nameStatus checks brew, including versioned formulae satisfying
+// constraint, and then PATH for a single package name.
+func (b *BrewManager) nameStatus(ctx context.Context, name, constraint string) models.PackageStatus {
+	if _, version, ok := b.installedKeg(ctx, name, constraint); ok {
+		return models.PackageStatus{Name: name, Status: "installed", Version: models.ParseVersion(version, "brew")}
+	}
+	// Not managed by brew, check if binary exists in PATH
+	if path, lookErr := execLookPath(name); lookErr == nil && path != "" {
+		status, source := "installed (external)", "external"
+		det, managed := DetectVersionManager(path)
+		if managed {
+			status, source = "installed ("+det.Manager+")", det.Manager
+		}
+		version := det.Version
+		if version == "" {
+			version = getBinaryVersion(ctx, name)
+		}
+		return models.PackageStatus{Name: name, Status: status, Version: models.ParseVersion(version, source)}
+	}
+	return models.PackageStatus{Name: name, Status: "missing"}
 }
 
 // getBinaryVersion runs '<binary> --version' and extracts the first version-like pattern (e.g., 1.2.3). Shows full output if ambiguous.
-func getBinaryVersion(binary string) string {
-	cmd := execCommand(binary, "--version")
+func getBinaryVersion(ctx context.Context, binary string) string {
+	cmd := execCommand(ctx, binary, "--version")
 	out, err := execOutput(cmd)
 	if err != nil {
 		return "unknown"
@@ -256,8 +399,172 @@ func getBinaryVersion(binary string) string {
 	return strings.TrimSpace(output)
 }
 
+// brewOutdated is the output of `brew outdated --json=v2`.
+type brewOutdated struct {
+	Formulae []brewOutdatedEntry `json:"formulae"`
+	Casks    []brewOutdatedEntry `json:"casks"`
+}
+
+type brewOutdatedEntry struct {
+	Name              string     `json:"name"`
+	InstalledVersions stringList `json:"installed_versions"`
+	CurrentVersion    string     `json:"current_version"`
+	Pinned            bool       `json:"pinned"`
+}
+
+// stringList decodes a JSON string or list of strings; brew has used both
+// for cask installed_versions.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = stringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// Outdated lists installed formulae and casks with newer versions available.
+func (b *BrewManager) Outdated(ctx context.Context) ([]models.OutdatedPackage, error) {
+	out, err := execOutput(execCommand(ctx, "brew", "outdated", "--json=v2"))
+	if err != nil {
+		return nil, fmt.Errorf("brew outdated failed: %w", err)
+	}
+	var parsed brewOutdated
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse brew outdated: %w", err)
+	}
+	var pkgs []models.OutdatedPackage
+	for _, e := range append(parsed.Formulae, parsed.Casks...) {
+		current := ""
+		if n := len(e.InstalledVersions); n > 0 {
+			current = e.InstalledVersions[n-1]
+		}
+		pkgs = append(pkgs, models.OutdatedPackage{Name: e.Name, Current: current, Latest: e.CurrentVersion, Pinned: e.Pinned})
+	}
+	return pkgs, nil
+}
+
 // IsAvailable checks if brew is available in the system PATH.
 func (b *BrewManager) IsAvailable() bool {
-	cmd := execCommand("brew", "--version")
+	cmd := execCommand(context.Background(), "brew", "--version")
 	return cmd.Run() == nil
-}
\ No newline at end of file
+}
+
+// brewInfo is the subset of `brew info --json=v2` used by Resolve and Versions.
+type brewInfo struct {
+	Formulae []struct {
+		Name     string `json:"name"`
+		Tap      string `json:"tap"`
+		Versions struct {
+			Stable string `json:"stable"`
+		} `json:"versions"`
+		VersionedFormulae []string `json:"versioned_formulae"`
+		Installed         []struct {
+			Version string `json:"version"`
+		} `json:"installed"`
+		URLs struct {
+			Stable struct {
+				Checksum string `json:"checksum"`
+			} `json:"stable"`
+		} `json:"urls"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token     string `json:"token"`
+		Tap       string `json:"tap"`
+		Installed string `json:"installed"`
+		SHA256    string `json:"sha256"`
+		Version   string `json:"version"`
+	} `json:"casks"`
+}
+
+func brewInfoFor(ctx context.Context, name string) (brewInfo, error) {
+	var info brewInfo
+	out, err := execOutput(execCommand(ctx, "brew", "info", "--json=v2", name))
+	if err != nil {
+		return info, fmt.Errorf("failed to query %s: %w", name, err)
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return info, fmt.Errorf("failed to parse brew info for %s: %w", name, err)
+	}
+	return info, nil
+}
+
+/*
+Versions lists the installable versions of the first name of pkg: the stable
+version followed by the versions of its versioned formulae (node@20 -> 20).
+*/
+func (b *BrewManager) Versions(ctx context.Context, pkg models.Package) ([]string, error) {
+	if len(pkg.Name) == 0 {
+		return nil, fmt.Errorf("package has no name")
+	}
+	info, err := brewInfoFor(ctx, pkg.Name[0])
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, f := range info.Formulae {
+		if f.Versions.Stable != "" {
+			versions = append(versions, f.Versions.Stable)
+		}
+		for _, vf := range f.VersionedFormulae {
+			if _, v, ok := strings.Cut(vf, "@"); ok {
+				versions = append(versions, v)
+			}
+		}
+	}
+	for _, c := range info.Casks {
+		if c.Version != "" {
+			versions = append(versions, c.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions found for %s", pkg.Name[0])
+	}
+	return versions, nil
+}
+
+// Resolve reports the installed version, tap and source checksum of the first
+// name of pkg, or of the versioned formula providing it, from `brew info
+// --json=v2`.
+func (b *BrewManager) Resolve(ctx context.Context, pkg models.Package) (models.Resolved, error) {
+	if len(pkg.Name) == 0 {
+		return models.Resolved{}, fmt.Errorf("package has no name")
+	}
+	name := pkg.Name[0]
+	keg := name
+	if installed, _, ok := b.installedKeg(ctx, name, pkg.Version); ok {
+		keg = installed
+	}
+	info, err := brewInfoFor(ctx, keg)
+	if err != nil {
+		return models.Resolved{}, err
+	}
+	for _, f := range info.Formulae {
+		if len(f.Installed) == 0 {
+			continue
+		}
+		res := models.Resolved{Version: f.Installed[len(f.Installed)-1].Version, Source: f.Tap}
+		if f.URLs.Stable.Checksum != "" {
+			res.Checksum = "sha256:" + f.URLs.Stable.Checksum
+		}
+		return res, nil
+	}
+	for _, c := range info.Casks {
+		if c.Installed == "" {
+			continue
+		}
+		res := models.Resolved{Version: c.Installed, Source: c.Tap}
+		if c.SHA256 != "" && c.SHA256 != "no_check" {
+			res.Checksum = "sha256:" + c.SHA256
+		}
+		return res, nil
+	}
+	return models.Resolved{}, fmt.Errorf("%s is not installed", name)
+}
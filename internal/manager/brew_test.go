@@ -1,8 +1,12 @@
 package manager
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
 	"voltig/internal/models"
 )
@@ -34,7 +38,7 @@ func TestBrewManager_GetStatus(t *testing.T) {
 		brewErr   error
 		lookPath  func(string) (string, error)
 		pkg       models.Package
-		want      models.PackageStatus
+		want      []models.PackageStatus
 	}{
 		{
 			name:    "installed via brew",
@@ -42,7 +46,7 @@ func TestBrewManager_GetStatus(t *testing.T) {
 			brewErr: nil,
 			lookPath: func(bin string) (string, error) { return "", exec.ErrNotFound },
 			pkg:     models.Package{Name: []string{"foo"}},
-			want:    models.PackageStatus{Name: "foo", Status: "installed", Version: "foo 1.2.3\n"},
+			want:    []models.PackageStatus{{Name: "foo", Status: "installed", Version: models.Version{Raw: "1.2.3", Semver: "1.2.3", Source: "brew"}}},
 		},
 		{
 			name:    "missing",
@@ -50,7 +54,7 @@ func TestBrewManager_GetStatus(t *testing.T) {
 			brewErr: exec.ErrNotFound,
 			lookPath: func(bin string) (string, error) { return "", exec.ErrNotFound },
 			pkg:     models.Package{Name: []string{"bar"}},
-			want:    models.PackageStatus{Name: "bar", Status: "missing"},
+			want:    []models.PackageStatus{{Name: "bar", Status: "missing"}},
 		},
 		{
 			name:    "external binary",
@@ -61,7 +65,7 @@ func TestBrewManager_GetStatus(t *testing.T) {
 				return "", exec.ErrNotFound
 			},
 			pkg: models.Package{Name: []string{"baz"}},
-			want: models.PackageStatus{Name: "baz", Status: "installed (external)", Version: "unknown"},
+			want: []models.PackageStatus{{Name: "baz", Status: "installed (external)", Version: models.Version{Raw: "unknown", Source: "external"}}},
 		},
 		{
 			name:    "nvm node",
@@ -73,7 +77,7 @@ func TestBrewManager_GetStatus(t *testing.T) {
 				return "", exec.ErrNotFound
 			},
 			pkg: models.Package{Name: []string{"node"}},
-			want: models.PackageStatus{Name: "node", Status: "installed (nvm)", Version: "unknown"},
+			want: []models.PackageStatus{{Name: "node", Status: "installed (nvm)", Version: models.Version{Raw: "18.16.0", Semver: "18.16.0", Source: "nvm"}}},
 		},
 		{
 			name:    "pyenv python",
@@ -85,20 +89,31 @@ func TestBrewManager_GetStatus(t *testing.T) {
 				return "", exec.ErrNotFound
 			},
 			pkg: models.Package{Name: []string{"python"}},
-			want: models.PackageStatus{Name: "python", Status: "installed (pyenv)", Version: "unknown"},
+			want: []models.PackageStatus{{Name: "python", Status: "installed (pyenv)", Version: models.Version{Raw: "3.9.1", Semver: "3.9.1", Source: "pyenv"}}},
+		},
+		{
+			name:    "volta shim",
+			brewOut: nil,
+			brewErr: exec.ErrNotFound,
+			lookPath: func(bin string) (string, error) {
+				if bin == "node" { return "/Users/test/.volta/bin/node", nil }
+				return "", exec.ErrNotFound
+			},
+			pkg: models.Package{Name: []string{"node"}},
+			want: []models.PackageStatus{{Name: "node", Status: "installed (volta)", Version: models.Version{Raw: "unknown", Source: "volta"}}},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			execCommand = func(name string, arg ...string) *exec.Cmd {
+			execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd {
 				return &exec.Cmd{}
 			}
 			execOutput = func(cmd *exec.Cmd) ([]byte, error) { return tt.brewOut, tt.brewErr }
 			execLookPath = tt.lookPath
 			origExecOutput := execOutput
 			defer func() { execOutput = origExecOutput }()
-			got, _ := b.GetStatus(tt.pkg)
+			got, _ := b.GetStatus(context.Background(), tt.pkg)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("got %+v, want %+v", got, tt.want)
 			}
@@ -106,29 +121,57 @@ func TestBrewManager_GetStatus(t *testing.T) {
 	}
 }
 
+func TestBrewManager_GetStatusMultiName(t *testing.T) {
+	origCommand, origOutput, origLookPath := execCommand, execOutput, execLookPath
+	defer func() { execCommand, execOutput, execLookPath = origCommand, origOutput, origLookPath }()
+	execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd {
+		return &exec.Cmd{Args: append([]string{name}, arg...)}
+	}
+	// node has two versions installed; rust isn't installed by brew
+	execOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		if cmd.Args[len(cmd.Args)-1] == "node" {
+			return []byte("node 20.10.0 20.11.1_1\n"), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	execLookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+
+	got, err := (&BrewManager{}).GetStatus(context.Background(), models.Package{Name: []string{"node", "rust"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.PackageStatus{
+		{Name: "node", Status: "installed", Version: models.Version{Raw: "20.11.1_1", Semver: "20.11.1", Source: "brew"}},
+		{Name: "rust", Status: "missing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
 func Test_getBinaryVersion(t *testing.T) {
 	// This will likely return "unknown" for a non-existent binary
-	version := getBinaryVersion("nonexistent-binary-xyz")
+	version := getBinaryVersion(context.Background(), "nonexistent-binary-xyz")
 	if version != "unknown" {
 		t.Errorf("Expected 'unknown' for missing binary, got %s", version)
 	}
 	// Simulate a binary that outputs a version string
 	origExecCommand := execCommand
 	defer func() { execCommand = origExecCommand }()
-	execCommand = func(name string, arg ...string) *exec.Cmd {
+	execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd {
 		return &exec.Cmd{}
 	}
 	execOutput = func(cmd *exec.Cmd) ([]byte, error) { return []byte("v1.2.3\n"), nil }
-	version = getBinaryVersion("dummy")
+	version = getBinaryVersion(context.Background(), "dummy")
 	if version != "v1.2.3" {
 		t.Errorf("Expected extracted version, got %s", version)
 	}
 	// Simulate a binary that outputs no version
-	execCommand = func(name string, arg ...string) *exec.Cmd {
+	execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd {
 		return &exec.Cmd{}
 	}
 	execOutput = func(cmd *exec.Cmd) ([]byte, error) { return []byte("no version here\n"), nil }
-	version = getBinaryVersion("dummy")
+	version = getBinaryVersion(context.Background(), "dummy")
 	if version != "no version here" {
 		t.Errorf("Expected fallback to full output, got %s", version)
 	}
@@ -142,7 +185,7 @@ func TestBrewManager_Install_OutputFn(t *testing.T) {
 	outputLines := []string{}
 	outputFn := func(s string) { outputLines = append(outputLines, s) }
 	// We expect this to fail since the package doesn't exist, but outputFn should still be valid
-	_ = b.Install(pkg, outputFn)
+	_ = b.Install(context.Background(), pkg, outputFn)
 	// No assertion on outputLines because brew is not actually called for a dummy package
 }
 
@@ -152,33 +195,158 @@ func TestBrewManager_Remove_OutputFn(t *testing.T) {
 	pkg := models.Package{Name: []string{"test"}}
 	outputLines := []string{}
 	outputFn := func(s string) { outputLines = append(outputLines, s) }
-	_ = b.Remove(pkg, outputFn)
+	_ = b.Remove(context.Background(), pkg, outputFn)
 }
 
-func Test_isNvmNode(t *testing.T) {
-	if !isNvmNode("/Users/test/.nvm/versions/node/v18.16.0/bin/node") {
-		t.Error("Expected true for nvm-managed path")
+func TestBrewManager_VersionedFormula(t *testing.T) {
+	origCommand, origOutput, origLookPath := execCommand, execOutput, execLookPath
+	defer func() { execCommand, execOutput, execLookPath = origCommand, origOutput, origLookPath }()
+	// A fake Homebrew: installs and removes edit kegs, list reads them
+	kegs := map[string]string{"node": "22.3.0"}
+	var ran []string
+	execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		ran = append(ran, strings.Join(arg, " "))
+		switch arg[0] {
+		case "install":
+			kegs[arg[1]] = "20.11.1"
+		case "uninstall":
+			delete(kegs, arg[1])
+		}
+		cmd := exec.CommandContext(ctx, "true")
+		cmd.Args = append([]string{name}, arg...)
+		return cmd
 	}
-	if isNvmNode("/usr/local/bin/node") {
-		t.Error("Expected false for non-nvm path")
+	execOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		var out []string
+		for keg, v := range kegs {
+			if len(cmd.Args) == 3 || cmd.Args[3] == keg {
+				out = append(out, keg+" "+v)
+			}
+		}
+		if len(out) == 0 {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(strings.Join(out, "\n") + "\n"), nil
 	}
-}
+	execLookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	b, ctx := &BrewManager{}, context.Background()
 
-func Test_isPyenvPython(t *testing.T) {
-	if !isPyenvPython("/Users/test/.pyenv/versions/3.9.1/bin/python") {
-		t.Error("Expected true for pyenv-managed path")
+	// PickVersion resolves ^20 to the node@20 formula
+	if err := b.Install(ctx, models.Package{Name: []string{"node"}, Version: "20"}, func(string) {}); err != nil {
+		t.Fatal(err)
+	}
+	pkg := models.Package{Name: []string{"node"}, Version: "^20"}
+	statuses, satisfied, err := Satisfied(ctx, b, pkg)
+	if err != nil || !satisfied || statuses[0].Version.Raw != "20.11.1" {
+		t.Errorf("node@20 not found for ^20: %+v, %v", statuses, err)
 	}
-	if isPyenvPython("/usr/bin/python") {
-		t.Error("Expected false for non-pyenv path")
+	if statuses, _ := b.GetStatus(ctx, models.Package{Name: []string{"node"}}); statuses[0].Version.Raw != "22.3.0" {
+		t.Errorf("expected plain node without a constraint, got %+v", statuses)
+	}
+	ran = nil
+	if err := b.Remove(ctx, pkg, func(string) {}); err != nil {
+		t.Fatal(err)
+	}
+	if ran[len(ran)-1] != "uninstall node@20" || kegs["node"] == "" {
+		t.Errorf("expected node@20 to be removed, ran %v", ran)
 	}
 }
 
-
-
 func TestBrewManager_Update(t *testing.T) {
 	b := &BrewManager{}
 	pkg := models.Package{Name: []string{"test"}}
 	// Should not panic, even if package does not exist
-	_ = b.Update(pkg)
+	_ = b.Update(context.Background(), pkg)
+}
+
+
+func TestBrewManager_Resolve(t *testing.T) {
+	b := &BrewManager{}
+	origCommand, origOutput := execCommand, execOutput
+	defer func() { execCommand, execOutput = origCommand, origOutput }()
+	execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd { return &exec.Cmd{} }
+
+	tests := []struct {
+		name    string
+		out     string
+		want    models.Resolved
+		wantErr bool
+	}{
+		{
+			name: "formula",
+			out:  `{"formulae":[{"name":"jq","tap":"homebrew/core","installed":[{"version":"1.7"},{"version":"1.7.1"}],"urls":{"stable":{"checksum":"abc"}}}],"casks":[]}`,
+			want: models.Resolved{Version: "1.7.1", Source: "homebrew/core", Checksum: "sha256:abc"},
+		},
+		{
+			name: "cask without checksum",
+			out:  `{"formulae":[],"casks":[{"token":"firefox","tap":"homebrew/cask","installed":"120.0","sha256":"no_check"}]}`,
+			want: models.Resolved{Version: "120.0", Source: "homebrew/cask"},
+		},
+		{
+			name:    "not installed",
+			out:     `{"formulae":[{"name":"jq","tap":"homebrew/core","installed":[]}],"casks":[]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execOutput = func(cmd *exec.Cmd) ([]byte, error) { return []byte(tt.out), nil }
+			got, err := b.Resolve(context.Background(), models.Package{Name: []string{"pkg"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
 }
 
+func TestBrewManager_Outdated(t *testing.T) {
+	b := &BrewManager{}
+	origCommand, origOutput := execCommand, execOutput
+	defer func() { execCommand, execOutput = origCommand, origOutput }()
+	execCommand = func(_ context.Context, name string, arg ...string) *exec.Cmd { return &exec.Cmd{} }
+	execOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		return []byte(`{"formulae":[{"name":"node","installed_versions":["20.1.0","20.10.0"],"current_version":"22.1.0","pinned":false}],
+"casks":[{"name":"firefox","installed_versions":"120.0","current_version":"121.0"}]}`), nil
+	}
+	got, err := b.Outdated(context.Background())
+	if err != nil {
+		t.Fatalf("Outdated failed: %v", err)
+	}
+	want := []models.OutdatedPackage{
+		{Name: "node", Current: "20.10.0", Latest: "22.1.0"},
+		{Name: "firefox", Current: "120.0", Latest: "121.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBrewError_Retryable(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		stderr []string
+		retry  bool
+		reason string
+	}{
+		{[]string{"Error: Another active Homebrew update process is already in progress."}, true, "lock held"},
+		{[]string{"==> Downloading https://ghcr.io/...", "curl: (56) Recv failure: Connection reset by peer"}, true, "download failed"},
+		{[]string{"Error: No available formula with the name \"nope\"."}, false, "no such package"},
+		{nil, true, "brew failed"},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("failed to install pkg: %w", &brewError{err: exit, stderr: tt.stderr})
+		var re RetryableError
+		if !errors.As(err, &re) {
+			t.Fatalf("brewError should be a RetryableError")
+		}
+		if retry, reason := re.Retryable(); retry != tt.retry || reason != tt.reason {
+			t.Errorf("Retryable(%q) = %v, %q; want %v, %q", tt.stderr, retry, reason, tt.retry, tt.reason)
+		}
+	}
+	if got := (&brewError{err: exit, stderr: []string{"a", "Error: last"}}).Error(); got != "exit status 1: Error: last" {
+		t.Errorf("Error() = %q", got)
+	}
+}
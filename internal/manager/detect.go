@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+/*
+VersionManager recognises the binaries a version manager such as nvm or
+pyenv puts in PATH. Patterns are matched against the slash-separated path of
+the binary and of its symlink target; a submatch, if any, is the active
+version.
+*/
+type VersionManager struct {
+	Name     string
+	Tools    []string // binaries it manages; empty for any
+	Patterns []*regexp.Regexp
+}
+
+// Detection is the version manager owning a binary.
+type Detection struct {
+	Manager string
+	Path    string // the binary as found in PATH
+	Version string // active version when the path tells, e.g. nvm's versions/node/v20.11.0
+}
+
+func pathPatterns(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		compiled[i] = regexp.MustCompile(p)
+	}
+	return compiled
+}
+
+// rustupProxies are the binaries rustup installs in ~/.cargo/bin; other
+// binaries there come from `cargo install`.
+var rustupProxies = []string{"cargo", "cargo-clippy", "cargo-fmt", "clippy-driver", "rls", "rust-analyzer", "rust-gdb", "rust-lldb", "rustc", "rustdoc", "rustfmt", "rustup"}
+
+// VersionManagers is the detector registry, checked in order. Add to it with
+// RegisterVersionManager.
+var VersionManagers = []VersionManager{
+	{Name: "nvm", Patterns: pathPatterns(`/\.nvm/versions/node/v?([^/]+)/`)},
+	{Name: "fnm", Patterns: pathPatterns(`/fnm/node-versions/v?([^/]+)/`, `/fnm_multishells/`)},
+	{Name: "volta", Patterns: pathPatterns(`/\.volta/tools/image/[^/]+/v?([^/]+)/`, `/\.volta/bin/`)},
+	{Name: "pyenv", Patterns: pathPatterns(`/\.pyenv/versions/([^/]+)/`, `/\.pyenv/shims/`)},
+	{Name: "rbenv", Patterns: pathPatterns(`/\.rbenv/versions/([^/]+)/`, `/\.rbenv/shims/`)},
+	{Name: "rustup", Tools: rustupProxies, Patterns: pathPatterns(`/\.rustup/toolchains/`, `/\.cargo/bin/`)},
+	{Name: "sdkman", Patterns: pathPatterns(`/\.sdkman/candidates/[^/]+/([^/]+)/`)},
+	{Name: "asdf", Patterns: pathPatterns(`/\.asdf/installs/[^/]+/v?([^/]+)/`, `/\.asdf/shims/`)},
+	{Name: "mise", Patterns: pathPatterns(`/mise/installs/[^/]+/v?([^/]+)/`, `/mise/shims/`)},
+}
+
+// RegisterVersionManager adds vm to the registry, ahead of the built-in ones.
+func RegisterVersionManager(vm VersionManager) {
+	VersionManagers = append([]VersionManager{vm}, VersionManagers...)
+}
+
+// manages reports whether vm manages binaries named like the one at path.
+func (vm VersionManager) manages(path string) bool {
+	if len(vm.Tools) == 0 {
+		return true
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, tool := range vm.Tools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+DetectVersionManager returns the version manager owning the binary at path,
+checking the path itself and, for shims and symlinks such as sdkman's
+`current`, the file it points to. The version is left empty when neither
+path names it.
+*/
+func DetectVersionManager(path string) (Detection, bool) {
+	candidates := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		candidates = append(candidates, resolved)
+	}
+	for _, vm := range VersionManagers {
+		if !vm.manages(path) {
+			continue
+		}
+		det, found := Detection{Manager: vm.Name, Path: path}, false
+		for _, candidate := range candidates {
+			slashed := filepath.ToSlash(candidate)
+			for _, p := range vm.Patterns {
+				m := p.FindStringSubmatch(slashed)
+				if m == nil {
+					continue
+				}
+				found = true
+				if len(m) > 1 && m[1] != "" && m[1] != "current" {
+					det.Version = m[1]
+				}
+			}
+		}
+		if found {
+			return det, true
+		}
+	}
+	return Detection{}, false
+}
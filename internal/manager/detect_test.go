@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestDetectVersionManager(t *testing.T) {
+	tests := []struct {
+		path    string
+		manager string
+		version string
+	}{
+		{"/Users/dev/.nvm/versions/node/v20.11.0/bin/node", "nvm", "20.11.0"},
+		{"/Users/dev/.local/share/fnm/node-versions/v18.19.0/installation/bin/node", "fnm", "18.19.0"},
+		{"/Users/dev/.local/state/fnm_multishells/123_456/bin/node", "fnm", ""},
+		{"/Users/dev/.volta/bin/node", "volta", ""},
+		{"/Users/dev/.volta/tools/image/node/20.11.0/bin/node", "volta", "20.11.0"},
+		{"/Users/dev/.pyenv/versions/3.12.1/bin/python", "pyenv", "3.12.1"},
+		{"/Users/dev/.pyenv/shims/python3", "pyenv", ""},
+		{"/Users/dev/.rbenv/versions/3.3.0/bin/ruby", "rbenv", "3.3.0"},
+		{"/Users/dev/.cargo/bin/rustc", "rustup", ""},
+		{"/Users/dev/.sdkman/candidates/java/21.0.2-tem/bin/java", "sdkman", "21.0.2-tem"},
+		{"/Users/dev/.asdf/installs/nodejs/20.11.0/bin/node", "asdf", "20.11.0"},
+		{"/Users/dev/.asdf/shims/node", "asdf", ""},
+		{"/Users/dev/.local/share/mise/installs/python/3.12.1/bin/python", "mise", "3.12.1"},
+		{"/Users/dev/.local/share/mise/shims/node", "mise", ""},
+		// cargo install puts other tools next to the rustup proxies
+		{"/Users/dev/.cargo/bin/rg", "", ""},
+		{"/opt/homebrew/bin/node", "", ""},
+	}
+	for _, tt := range tests {
+		det, ok := DetectVersionManager(tt.path)
+		if ok != (tt.manager != "") || det.Manager != tt.manager || det.Version != tt.version {
+			t.Errorf("DetectVersionManager(%s) = %+v, %v; want %s %q", tt.path, det, ok, tt.manager, tt.version)
+		}
+	}
+}
+
+func TestDetectVersionManager_Symlink(t *testing.T) {
+	// sdkman puts candidates/<tool>/current in PATH, a link to the active version
+	root := t.TempDir()
+	bin := filepath.Join(root, ".sdkman", "candidates", "java", "21.0.2-tem", "bin")
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "java"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	current := filepath.Join(root, ".sdkman", "candidates", "java", "current")
+	if err := os.Symlink(filepath.Join(root, ".sdkman", "candidates", "java", "21.0.2-tem"), current); err != nil {
+		t.Fatal(err)
+	}
+	det, ok := DetectVersionManager(filepath.Join(current, "bin", "java"))
+	if !ok || det.Manager != "sdkman" || det.Version != "21.0.2-tem" {
+		t.Errorf("got %+v, %v; want sdkman 21.0.2-tem", det, ok)
+	}
+}
+
+func TestRegisterVersionManager(t *testing.T) {
+	orig := VersionManagers
+	defer func() { VersionManagers = orig }()
+	RegisterVersionManager(VersionManager{Name: "nodenv", Tools: []string{"node"}, Patterns: []*regexp.Regexp{regexp.MustCompile(`/\.nodenv/versions/([^/]+)/`)}})
+	det, ok := DetectVersionManager("/Users/dev/.nodenv/versions/20.11.0/bin/node")
+	if !ok || det.Manager != "nodenv" || det.Version != "20.11.0" {
+		t.Errorf("got %+v, %v; want nodenv 20.11.0", det, ok)
+	}
+}
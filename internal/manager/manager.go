@@ -1,23 +1,246 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 	"voltig/internal/models"
+	"voltig/internal/semver"
 	"voltig/pkg/logger"
 )
 
+// Package operations, as passed to CommandPlanner and DryRun.
+const (
+	OpInstall = "install"
+	OpUpdate  = "update"
+	OpRemove  = "remove"
+)
+
 // PackageManager defines the interface for a system package manager used by Voltig CLI.
 type PackageManager interface {
-	Install(pkg models.Package, outputFn func(string)) error
-	Update(pkg models.Package) error
-	Remove(pkg models.Package, outputFn func(string)) error
-	GetStatus(pkg models.Package) (models.PackageStatus, error)
+	Install(ctx context.Context, pkg models.Package, outputFn func(string)) error
+	Update(ctx context.Context, pkg models.Package) error
+	Remove(ctx context.Context, pkg models.Package, outputFn func(string)) error
+	// GetStatus returns the status of each name of pkg, in order
+	GetStatus(ctx context.Context, pkg models.Package) ([]models.PackageStatus, error)
 	IsAvailable() bool
 }
 
+/*
+Resolver is implemented by managers that can report the exact installed version
+of a package, with its source and checksum, for voltig.lock.
+*/
+type Resolver interface {
+	Resolve(ctx context.Context, pkg models.Package) (models.Resolved, error)
+}
+
+// Resolve returns the installed version of the first name of pkg using m's
+// Resolver if it has one, falling back to the version reported by GetStatus.
+func Resolve(ctx context.Context, m PackageManager, pkg models.Package) (models.Resolved, error) {
+	if r, ok := m.(Resolver); ok {
+		return r.Resolve(ctx, pkg)
+	}
+	statuses, err := m.GetStatus(ctx, pkg)
+	if err != nil {
+		return models.Resolved{}, err
+	}
+	if len(statuses) == 0 || statuses[0].Status == "missing" {
+		return models.Resolved{}, fmt.Errorf("%s is not installed", pkg.Name[0])
+	}
+	return models.Resolved{Version: statuses[0].Version.Raw}, nil
+}
+
+/*
+VersionLister is implemented by managers that can list the versions of a
+package available to install, newest first.
+*/
+type VersionLister interface {
+	Versions(ctx context.Context, pkg models.Package) ([]string, error)
+}
+
+/*
+PickVersion resolves the version constraint of pkg to a concrete version the
+manager can install. Bare versions are passed through; ranges such as `^1.4`
+pick the newest satisfying version when m is a VersionLister, or fall back to
+the latest version otherwise. The newest available version is returned as
+"latest".
+*/
+func PickVersion(ctx context.Context, m PackageManager, pkg models.Package) (models.Package, error) {
+	c, err := semver.ParseConstraint(pkg.Version)
+	if err != nil {
+		return pkg, err
+	}
+	if c.Any() {
+		return pkg, nil
+	}
+	lister, ok := m.(VersionLister)
+	if !ok {
+		if !c.Exact() {
+			logger.Warn("Manager cannot list versions; installing latest", "package", pkg.Name[0], "constraint", c.String())
+			pkg.Version = "latest"
+		}
+		return pkg, nil
+	}
+	available, err := lister.Versions(ctx, pkg)
+	if err != nil {
+		return pkg, err
+	}
+	best, found := c.Best(available)
+	if !found {
+		return pkg, fmt.Errorf("no available version of %s satisfies %s (available: %s)", pkg.Name[0], c, strings.Join(available, ", "))
+	}
+	if newest, _ := (semver.Constraint{}).Best(available); best.Compare(newest) == 0 {
+		pkg.Version = "latest"
+	} else {
+		pkg.Version = best.String()
+	}
+	return pkg, nil
+}
+
+/*
+LockedVersion is PickVersion for an installed version recorded earlier in a
+lockfile or snapshot, e.g. 22.3.0 or Homebrew's 1.7.1_1, in pkg.Version. Managers listing their
+versions usually can't install it as such: it becomes latest when it is the
+newest available version, or the available version naming its release line,
+such as 22 for Homebrew's node@22. It fails when neither matches. Managers
+that can't list versions get the version without its revision.
+*/
+func LockedVersion(ctx context.Context, m PackageManager, pkg models.Package) (models.Package, error) {
+	locked, err := semver.Parse(pkg.Version)
+	if err != nil {
+		return pkg, fmt.Errorf("invalid locked version %q of %s", pkg.Version, pkg.Name[0])
+	}
+	lister, ok := m.(VersionLister)
+	if !ok {
+		pkg.Version = locked.Original
+		return pkg, nil
+	}
+	available, err := lister.Versions(ctx, pkg)
+	if err != nil {
+		return pkg, err
+	}
+	if newest, _ := (semver.Constraint{}).Best(available); newest.Compare(locked) == 0 {
+		pkg.Version = "latest"
+		return pkg, nil
+	}
+	for _, v := range available {
+		if c, err := semver.ParseConstraint(v); err == nil && c.Exact() && c.Check(locked) {
+			pkg.Version = v
+			return pkg, nil
+		}
+	}
+	return pkg, fmt.Errorf("locked version %s of %s can no longer be installed (available: %s); run `voltig lock --update`", pkg.Version, pkg.Name[0], strings.Join(available, ", "))
+}
+
+/*
+Outdater is implemented by managers that can list their installed packages
+with newer versions available, e.g. `brew outdated --json`.
+*/
+type Outdater interface {
+	Outdated(ctx context.Context) ([]models.OutdatedPackage, error)
+}
+
+/*
+CommandPlanner is implemented by managers that can report the exact command
+lines an operation would run, for --dry-run.
+*/
+type CommandPlanner interface {
+	Commands(op string, pkg models.Package) []string
+}
+
+/*
+DryRun writes, for each package name, its current status and the commands op
+would run, without running them. Managers that aren't CommandPlanners get a
+generic description of each package instead. The package's pre and post hooks
+for op are listed around its commands.
+*/
+func DryRun(ctx context.Context, w io.Writer, m PackageManager, op string, pkgs []models.Package) error {
+	planner, canPlan := m.(CommandPlanner)
+	for _, pkg := range pkgs {
+		current := make([]string, len(pkg.Name))
+		statuses, err := m.GetStatus(ctx, pkg)
+		for i := range current {
+			current[i] = "unknown"
+			if err == nil && i < len(statuses) {
+				current[i] = statuses[i].Status
+				if v := statuses[i].Version.String(); v != "" {
+					current[i] += " " + v
+				}
+			}
+		}
+		name := strings.Join(pkg.Name, ", ")
+		hookLines := func(stage string) error {
+			for _, line := range pkg.Hooks[stage] {
+				if _, err := fmt.Fprintf(w, "[dry-run] %s (%s): %s: %s\n", name, strings.Join(current, ", "), stage, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := hookLines("pre_" + op); err != nil {
+			return err
+		}
+		if !canPlan {
+			if _, err := fmt.Fprintf(w, "[dry-run] %s (%s): %s %s (%s)\n", name, strings.Join(current, ", "), op, name, pkg.Manager); err != nil {
+				return err
+			}
+		}
+		for i := 0; canPlan && i < len(pkg.Name); i++ {
+			single := pkg
+			single.Name = []string{pkg.Name[i]}
+			for _, line := range planner.Commands(op, single) {
+				if _, err := fmt.Fprintf(w, "[dry-run] %s (%s): %s\n", pkg.Name[i], current[i], line); err != nil {
+					return err
+				}
+			}
+		}
+		if err := hookLines("post_" + op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellJoin renders args as a shell command line, quoting where needed.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"$`\\|&;<>()*?[]#~") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
+
+/*
+Satisfied reports whether every name of pkg is installed, by this or any other
+means, at a version satisfying its version constraint. It relies only on
+GetStatus, so it works for every manager. The statuses are returned for
+callers that need them.
+*/
+func Satisfied(ctx context.Context, m PackageManager, pkg models.Package) ([]models.PackageStatus, bool, error) {
+	statuses, err := m.GetStatus(ctx, pkg)
+	if err != nil {
+		return statuses, false, err
+	}
+	c, err := semver.ParseConstraint(pkg.Version)
+	if err != nil {
+		return statuses, false, err
+	}
+	for _, status := range statuses {
+		if !strings.HasPrefix(status.Status, "installed") || semver.Status(c, status.Version.String()) != semver.StatusOK {
+			return statuses, false, nil
+		}
+	}
+	return statuses, len(statuses) > 0, nil
+}
+
 // ForOS returns the appropriate PackageManager for the current OS.
 func ForOS() PackageManager {
 	if runtime.GOOS == "darwin" {
@@ -29,14 +252,59 @@ func ForOS() PackageManager {
 	return nil
 }
 
-// PkgOperation performs an operation (install, remove, etc.) on a list of packages using the provided opFunc.
-func PkgOperation(opName, opPast string, pkgs []models.Package, opFunc func(models.Package, func(string)) error) (successes, failures []string) {
+// ForName returns the PackageManager for a `manager:` value such as "brew",
+// or nil if voltig doesn't support it or it isn't available here.
+func ForName(name string) PackageManager {
+	if name == "brew" {
+		bm := &BrewManager{}
+		if bm.IsAvailable() {
+			return bm
+		}
+	}
+	return nil
+}
+
+// PackageTimeout bounds each package operation run by PkgOperation; zero
+// means no limit. Set from --package-timeout or a package's timeout field.
+var PackageTimeout time.Duration
+
+/*
+RetryableError is implemented by errors a manager has classified as transient,
+such as a lock held by another process or a failed download, or as permanent,
+such as an unknown package. Reason describes the failure in a few words for
+the retry log. Errors that don't implement it are retried.
+*/
+type RetryableError interface {
+	error
+	Retryable() (retry bool, reason string)
+}
+
+// DefaultRetryBackoff is the delay before the first retry of a package that
+// sets retries but no retry_backoff.
+var DefaultRetryBackoff = 2 * time.Second
+
+// maxRetryBackoff caps the doubling delay between attempts.
+const maxRetryBackoff = 5 * time.Minute
+
+/*
+PkgOperation performs an operation (install, remove, etc.) on a list of packages
+using the provided opFunc. Each call gets ctx bounded by the package's timeout
+(or PackageTimeout). Once ctx is cancelled, the interrupted package and all
+those not yet attempted are returned as cancelled rather than failed.
+*/
+func PkgOperation(ctx context.Context, opName, opPast string, pkgs []models.Package, opFunc func(context.Context, models.Package, func(string)) error) (successes, failures, cancelled []string) {
 	total := len(pkgs)
 	for i, pkg := range pkgs {
+		if ctx.Err() != nil {
+			cancelled = append(cancelled, pkg.Name...)
+			continue
+		}
 		// For packages with multiple names, we'll log all names
 		pkgNameStr := strings.Join(pkg.Name, ", ")
 		logger.Info(opName+" package", "package", pkgNameStr, "progress", fmt.Sprintf("%d/%d", i+1, total))
 
+		// Output is only redrawn on the current line once something was written
+		wrote := false
 		showOutput := func(line string) {
 			lines := strings.Split(line, "\n")
 			last := ""
@@ -47,26 +315,92 @@ func PkgOperation(opName, opPast string, pkgs []models.Package, opFunc func(mode
 				}
 			}
 			if last != "" {
-				if _, err := fmt.Fprintf(os.Stdout, "\r[%s] %s", pkgNameStr, last); err != nil {
+				wrote = true
+				if _, err := fmt.Fprintf(os.Stdout, "\r\033[K[%s] %s", pkgNameStr, last); err != nil {
 					logger.Error("Failed to write to stdout", "error", err)
 				}
 			}
 		}
-		err := opFunc(pkg, showOutput)
+		err := retry(ctx, pkg, func(ctx context.Context) error {
+			return runWithTimeout(ctx, pkg, func(ctx context.Context) error {
+				return opFunc(ctx, pkg, showOutput)
+			})
+		})
 
-		if _, err := fmt.Fprint(os.Stdout, "\n"); err != nil {
-			logger.Error("Failed to write newline to stdout", "error", err)
+		if wrote {
+			if _, err := fmt.Fprint(os.Stdout, "\n"); err != nil {
+				logger.Error("Failed to write newline to stdout", "error", err)
+			}
 		}
 
-		if err != nil {
+		switch {
+		case err != nil && ctx.Err() != nil:
+			logger.Warn("Cancelled "+opName+" package", "package", pkgNameStr)
+			cancelled = append(cancelled, pkg.Name...)
+		case err != nil:
 			logger.Error("Failed to "+opName+" package", "package", pkgNameStr, "error", err)
 			// Add each individual package name to the failures list
 			failures = append(failures, pkg.Name...)
-		} else {
+		default:
 			logger.Info("Successfully "+opPast+" package", "package", pkgNameStr)
 			// Add each individual package name to the successes list
 			successes = append(successes, pkg.Name...)
 		}
 	}
-	return successes, failures
-}
\ No newline at end of file
+	return successes, failures, cancelled
+}
+
+/*
+retry calls fn, retrying up to pkg.Retries times while it fails with a
+retryable error. The delay starts at pkg.RetryBackoff and doubles after each
+attempt. Each retry is logged with the reason of the failure; cancelling ctx
+stops retrying.
+*/
+func retry(ctx context.Context, pkg models.Package, fn func(context.Context) error) error {
+	backoff := pkg.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	name := strings.Join(pkg.Name, ", ")
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || ctx.Err() != nil || attempt > pkg.Retries {
+			return err
+		}
+		retryable, reason := true, "failed"
+		var re RetryableError
+		if errors.As(err, &re) {
+			retryable, reason = re.Retryable()
+		}
+		if !retryable {
+			logger.Warn("Not retrying package", "package", name, "reason", reason)
+			return err
+		}
+		logger.Warn("Retrying package", "package", name, "attempt", fmt.Sprintf("%d/%d", attempt+1, pkg.Retries+1), "reason", reason, "in", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// runWithTimeout calls fn with ctx bounded by pkg's timeout, falling back to
+// PackageTimeout, and reports a timeout as such rather than "signal: killed".
+func runWithTimeout(ctx context.Context, pkg models.Package, fn func(context.Context) error) error {
+	timeout := PackageTimeout
+	if pkg.Timeout > 0 {
+		timeout = pkg.Timeout
+	}
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(opCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
@@ -1,8 +1,12 @@
 package manager
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 	"voltig/internal/models"
 )
 
@@ -12,31 +16,30 @@ type mockManager struct {
 	installed map[string]bool
 }
 
-func (m *mockManager) Install(pkg models.Package, _ func(string)) error {
+func (m *mockManager) Install(_ context.Context, pkg models.Package, _ func(string)) error {
 	m.installed[pkg.Name[0]] = true
 	return nil
 }
-func (m *mockManager) Update(pkg models.Package, _ func(string)) error {
+func (m *mockManager) Update(_ context.Context, pkg models.Package) error {
 	m.installed[pkg.Name[0]] = true
 	return nil
 }
-func (m *mockManager) Remove(pkg models.Package, _ func(string)) error {
+func (m *mockManager) Remove(_ context.Context, pkg models.Package, _ func(string)) error {
 	m.installed[pkg.Name[0]] = false
 	return nil
 }
-func (m *mockManager) GetStatus(pkg models.Package) (models.PackageStatus, error) {
+func (m *mockManager) GetStatus(_ context.Context, pkg models.Package) ([]models.PackageStatus, error) {
 	if m.installed[pkg.Name[0]] {
-		return models.PackageStatus{Name: pkg.Name[0], Version: pkg.Version, Status: "installed"}, nil
+		return []models.PackageStatus{{Name: pkg.Name[0], Version: models.ParseVersion(pkg.Version, "mock"), Status: "installed"}}, nil
 	}
-	return models.PackageStatus{Name: pkg.Name[0], Version: pkg.Version, Status: "missing"}, errors.New("not installed")
+	return []models.PackageStatus{{Name: pkg.Name[0], Status: "missing"}}, errors.New("not installed")
 }
 func (m *mockManager) IsAvailable() bool { return true }
 
-
 func TestInstall(t *testing.T) {
 	mgr := &mockManager{installed: make(map[string]bool)}
 	pkg := models.Package{Name: []string{"foo"}, Version: "1.0.0"}
-	if err := mgr.Install(pkg, nil); err != nil {
+	if err := mgr.Install(context.Background(), pkg, nil); err != nil {
 		t.Fatalf("Install failed: %v", err)
 	}
 	if !mgr.installed["foo"] {
@@ -47,7 +50,7 @@ func TestInstall(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	mgr := &mockManager{installed: make(map[string]bool)}
 	pkg := models.Package{Name: []string{"foo"}, Version: "1.0.0"}
-	if err := mgr.Update(pkg, nil); err != nil {
+	if err := mgr.Update(context.Background(), pkg); err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
 
@@ -63,7 +66,7 @@ func TestUpdate(t *testing.T) {
 func TestRemove(t *testing.T) {
 	mgr := &mockManager{installed: map[string]bool{"foo": true}}
 	pkg := models.Package{Name: []string{"foo"}, Version: "1.0.0"}
-	if err := mgr.Remove(pkg, nil); err != nil {
+	if err := mgr.Remove(context.Background(), pkg, nil); err != nil {
 		t.Fatalf("Remove failed: %v", err)
 	}
 	if mgr.installed["foo"] {
@@ -74,14 +77,14 @@ func TestRemove(t *testing.T) {
 func TestGetStatus(t *testing.T) {
 	mgr := &mockManager{installed: map[string]bool{"foo": true}}
 	pkg := models.Package{Name: []string{"foo"}, Version: "1.0.0"}
-	status, err := mgr.GetStatus(pkg)
-	if err != nil || status.Status != "installed" {
-		t.Errorf("Expected installed, got %v, err=%v", status.Status, err)
+	statuses, err := mgr.GetStatus(context.Background(), pkg)
+	if err != nil || statuses[0].Status != "installed" {
+		t.Errorf("Expected installed, got %v, err=%v", statuses, err)
 	}
 	pkgMissing := models.Package{Name: []string{"bar"}, Version: "1.0.0"}
-	status, err = mgr.GetStatus(pkgMissing)
-	if err == nil || status.Status != "missing" {
-		t.Errorf("Expected missing, got %v, err=%v", status.Status, err)
+	statuses, err = mgr.GetStatus(context.Background(), pkgMissing)
+	if err == nil || statuses[0].Status != "missing" {
+		t.Errorf("Expected missing, got %v, err=%v", statuses, err)
 	}
 }
 
@@ -95,31 +98,319 @@ func TestIsAvailable(t *testing.T) {
 func TestPkgOperation(t *testing.T) {
 	mgr := &mockManager{installed: make(map[string]bool)}
 	pkgs := []models.Package{{Name: []string{"foo"}}, {Name: []string{"bar"}}}
-	dummyOp := func(pkg models.Package, _ func(string)) error {
+	dummyOp := func(_ context.Context, pkg models.Package, _ func(string)) error {
 		mgr.installed[pkg.Name[0]] = true
 		return nil
 	}
-	successes, failures := PkgOperation("Install", "installed", pkgs, dummyOp)
+	successes, failures, _ := PkgOperation(context.Background(), "Install", "installed", pkgs, dummyOp)
 	if len(successes) != 2 || len(failures) != 0 {
 		t.Errorf("Expected all installs to succeed, got successes=%v failures=%v", successes, failures)
 	}
 	// Simulate one failure
-	failOp := func(pkg models.Package, _ func(string)) error {
+	failOp := func(_ context.Context, pkg models.Package, _ func(string)) error {
 		if pkg.Name[0] == "bar" {
 			return errors.New("fail")
 		}
 		mgr.installed[pkg.Name[0]] = true
 		return nil
 	}
-	successes, failures = PkgOperation("Install", "installed", pkgs, failOp)
+	successes, failures, _ = PkgOperation(context.Background(), "Install", "installed", pkgs, failOp)
 	if len(successes) != 1 || len(failures) != 1 {
 		t.Errorf("Expected one success and one failure, got successes=%v failures=%v", successes, failures)
 	}
 	// Explicitly cover the return statement (line 67 in manager.go)
 	var emptyPkgs []models.Package
-	ss, ff := PkgOperation("Install", "installed", emptyPkgs, dummyOp)
+	ss, ff, _ := PkgOperation(context.Background(), "Install", "installed", emptyPkgs, dummyOp)
 	if len(ss) != 0 || len(ff) != 0 {
 		t.Errorf("Expected empty slices for empty input, got successes=%v failures=%v", ss, ff)
 	}
 }
 
+func TestPkgOperation_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pkgs := []models.Package{{Name: []string{"foo"}}, {Name: []string{"bar"}}, {Name: []string{"baz"}}}
+	// foo succeeds, bar is interrupted mid-operation, baz is never started
+	op := func(ctx context.Context, pkg models.Package, _ func(string)) error {
+		if pkg.Name[0] == "bar" {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+	successes, failures, cancelled := PkgOperation(ctx, "Install", "installed", pkgs, op)
+	if len(successes) != 1 || len(failures) != 0 || len(cancelled) != 2 {
+		t.Errorf("got successes=%v failures=%v cancelled=%v", successes, failures, cancelled)
+	}
+}
+
+func TestPkgOperation_Timeout(t *testing.T) {
+	orig := PackageTimeout
+	PackageTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { PackageTimeout = orig })
+	pkgs := []models.Package{{Name: []string{"slow"}, Timeout: time.Millisecond}, {Name: []string{"fast"}}}
+	op := func(ctx context.Context, _ models.Package, _ func(string)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	// Both time out: slow by its own timeout, fast by PackageTimeout
+	successes, failures, cancelled := PkgOperation(context.Background(), "Install", "installed", pkgs, op)
+	if len(successes) != 0 || len(failures) != 2 || len(cancelled) != 0 {
+		t.Errorf("timeouts should be failures, got successes=%v failures=%v cancelled=%v", successes, failures, cancelled)
+	}
+}
+
+// flakyError is a classified error for retry tests.
+type flakyError struct{ retry bool }
+
+func (e flakyError) Error() string             { return "flaky" }
+func (e flakyError) Retryable() (bool, string) { return e.retry, "test" }
+
+func TestPkgOperation_Retries(t *testing.T) {
+	attempts := make(map[string]int)
+	// flaky fails twice, broken fails permanently, unknown fails with an unclassified error
+	op := func(_ context.Context, pkg models.Package, _ func(string)) error {
+		attempts[pkg.Name[0]]++
+		switch pkg.Name[0] {
+		case "flaky":
+			if attempts["flaky"] < 3 {
+				return flakyError{retry: true}
+			}
+		case "broken":
+			return fmt.Errorf("install: %w", flakyError{retry: false})
+		case "unknown":
+			return errors.New("boom")
+		}
+		return nil
+	}
+	pkgs := []models.Package{
+		{Name: []string{"flaky"}, Retries: 2, RetryBackoff: time.Millisecond},
+		{Name: []string{"broken"}, Retries: 2, RetryBackoff: time.Millisecond},
+		{Name: []string{"unknown"}, Retries: 1, RetryBackoff: time.Millisecond},
+	}
+	successes, failures, _ := PkgOperation(context.Background(), "Install", "installed", pkgs, op)
+	if len(successes) != 1 || len(failures) != 2 {
+		t.Errorf("got successes=%v failures=%v", successes, failures)
+	}
+	if attempts["flaky"] != 3 || attempts["broken"] != 1 || attempts["unknown"] != 2 {
+		t.Errorf("unexpected attempts: %v", attempts)
+	}
+}
+
+func TestPkgOperation_RetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	op := func(context.Context, models.Package, func(string)) error {
+		attempts++
+		cancel()
+		return flakyError{retry: true}
+	}
+	pkgs := []models.Package{{Name: []string{"pkg"}, Retries: 5, RetryBackoff: time.Hour}}
+	_, _, cancelled := PkgOperation(ctx, "Install", "installed", pkgs, op)
+	if attempts != 1 || len(cancelled) != 1 {
+		t.Errorf("expected cancellation to stop retries, got attempts=%d cancelled=%v", attempts, cancelled)
+	}
+}
+
+// stubManager is a PackageManager whose operations all succeed.
+type stubManager struct{}
+
+func (stubManager) Install(context.Context, models.Package, func(string)) error { return nil }
+func (stubManager) Update(context.Context, models.Package) error                { return nil }
+func (stubManager) Remove(context.Context, models.Package, func(string)) error  { return nil }
+func (stubManager) GetStatus(_ context.Context, pkg models.Package) ([]models.PackageStatus, error) {
+	var statuses []models.PackageStatus
+	for _, name := range pkg.Name {
+		statuses = append(statuses, models.PackageStatus{Name: name, Status: "missing"})
+	}
+	return statuses, nil
+}
+func (stubManager) IsAvailable() bool { return true }
+
+// listingManager is a stubManager that can list available versions.
+type listingManager struct {
+	stubManager
+	versions []string
+}
+
+func (m *listingManager) Versions(context.Context, models.Package) ([]string, error) {
+	return m.versions, nil
+}
+
+func TestPickVersion(t *testing.T) {
+	lister := &listingManager{versions: []string{"22.1.0", "20", "18"}}
+	plain := stubManager{}
+	tests := []struct {
+		name    string
+		m       PackageManager
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"latest untouched", lister, "latest", "latest", false},
+		{"range picks newest match", lister, "^20", "20", false},
+		{"newest available is latest", lister, ">=20", "latest", false},
+		{"nothing satisfies", lister, "^16", "", true},
+		{"invalid constraint", lister, ">=abc", "", true},
+		{"exact without lister", plain, "1.7", "1.7", false},
+		{"range without lister", plain, "^1.4", "latest", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PickVersion(context.Background(), tt.m, models.Package{Name: []string{"node"}, Version: tt.version})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got.Version != tt.want {
+				t.Errorf("got version %q, want %q", got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockedVersion(t *testing.T) {
+	lister := &listingManager{versions: []string{"22.3.0", "20", "18"}}
+	tests := []struct {
+		name    string
+		m       PackageManager
+		locked  string
+		want    string
+		wantErr bool
+	}{
+		{"newest is latest", lister, "22.3.0", "latest", false},
+		{"brew revision is latest", lister, "22.3.0_1", "latest", false},
+		{"older release line", lister, "20.11.1", "20", false},
+		{"superseded patch", lister, "22.2.0", "", true},
+		{"unknown release line", lister, "16.20.2", "", true},
+		{"invalid version", lister, "head", "", true},
+		{"exact without lister", stubManager{}, "1.7.1_1", "1.7.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LockedVersion(context.Background(), tt.m, models.Package{Name: []string{"node"}, Version: tt.locked})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got.Version != tt.want {
+				t.Errorf("got version %q, want %q", got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	pkgs := []models.Package{{Name: []string{"node"}, Manager: "brew", Version: "20"}, {Name: []string{"jq", "yq"}, Manager: "brew"}}
+	if err := DryRun(context.Background(), &buf, &brewStatusStub{}, OpInstall, pkgs); err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	want := "[dry-run] node (installed 18.0.0): brew install node@20\n" +
+		"[dry-run] jq (missing): brew install jq\n" +
+		"[dry-run] yq (missing): brew install yq\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := DryRun(context.Background(), &buf, stubManager{}, OpRemove, pkgs[:1]); err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if buf.String() != "[dry-run] node (missing): remove node (brew)\n" {
+		t.Errorf("unexpected generic plan: %q", buf.String())
+	}
+
+	buf.Reset()
+	hooked := models.Package{Name: []string{"pre-commit"}, Manager: "brew", Hooks: map[string][]string{
+		"pre_install": {"echo start"}, "post_install": {"pre-commit install"}, "post_remove": {"rm -f .git/hooks/pre-commit"},
+	}}
+	if err := DryRun(context.Background(), &buf, &brewStatusStub{}, OpInstall, []models.Package{hooked}); err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	want = "[dry-run] pre-commit (missing): pre_install: echo start\n" +
+		"[dry-run] pre-commit (missing): brew install pre-commit\n" +
+		"[dry-run] pre-commit (missing): post_install: pre-commit install\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+// brewStatusStub is a BrewManager with canned statuses.
+type brewStatusStub struct{ BrewManager }
+
+func (b *brewStatusStub) GetStatus(_ context.Context, pkg models.Package) ([]models.PackageStatus, error) {
+	var statuses []models.PackageStatus
+	for _, name := range pkg.Name {
+		status := models.PackageStatus{Name: name, Status: "missing"}
+		if name == "node" {
+			status = models.PackageStatus{Name: name, Status: "installed", Version: models.ParseVersion("18.0.0", "brew")}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func TestBrewCommands(t *testing.T) {
+	b := &BrewManager{}
+	pkg := models.Package{Name: []string{"node"}, Version: "latest"}
+	tests := map[string]string{
+		OpInstall: "brew install node",
+		OpUpdate:  "brew upgrade node",
+		OpRemove:  "HOMEBREW_NO_AUTO_UPDATE=1 brew uninstall node",
+	}
+	for op, want := range tests {
+		if got := b.Commands(op, pkg); len(got) != 1 || got[0] != want {
+			t.Errorf("Commands(%s) = %v, want %q", op, got, want)
+		}
+	}
+	if got := b.Commands(OpInstall, models.Package{Name: []string{"jq"}, Reinstall: true}); got[0] != "brew reinstall jq" {
+		t.Errorf("Commands(reinstall) = %v", got)
+	}
+	if got := shellJoin([]string{"install", "a b", "it's"}); got != `install 'a b' 'it'\''s'` {
+		t.Errorf("shellJoin = %s", got)
+	}
+}
+
+// versionedStub reports every package as installed at version.
+type versionedStub struct {
+	stubManager
+	status  string
+	version string
+}
+
+func (m versionedStub) GetStatus(_ context.Context, pkg models.Package) ([]models.PackageStatus, error) {
+	var statuses []models.PackageStatus
+	for _, name := range pkg.Name {
+		statuses = append(statuses, models.PackageStatus{Name: name, Status: m.status, Version: models.ParseVersion(m.version, "stub")})
+	}
+	return statuses, nil
+}
+
+func TestSatisfied(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          PackageManager
+		constraint string
+		want       bool
+	}{
+		{"missing", stubManager{}, "latest", false},
+		{"installed latest", versionedStub{status: "installed", version: "1.7.1"}, "latest", true},
+		{"external satisfies", versionedStub{status: "installed (nvm)", version: "v20.11.1"}, "^20", true},
+		{"too old", versionedStub{status: "installed", version: "18.19.0"}, "^20", false},
+		{"exact match", versionedStub{status: "installed", version: "1.7.1"}, "1.7.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := Satisfied(context.Background(), tt.m, models.Package{Name: []string{"pkg"}, Version: tt.constraint})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Satisfied = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// Every name must be satisfied
+	if _, got, _ := Satisfied(context.Background(), &brewStatusStub{}, models.Package{Name: []string{"node", "jq"}}); got {
+		t.Error("Satisfied = true with jq missing")
+	}
+}
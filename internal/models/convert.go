@@ -3,15 +3,39 @@ Package models provides data models for Voltig CLI.
 */
 package models
 
-import "voltig/config"
+import (
+	"time"
+	"voltig/config"
+)
 
 // ToModel converts a config.Package to a models.Package.
+// Invalid durations are ignored here; lint reports them.
 func ToModel(pkg config.Package) Package {
+	timeout, _ := time.ParseDuration(pkg.Timeout)
+	backoff, _ := time.ParseDuration(pkg.RetryBackoff)
+	retries := 0
+	if pkg.Retries != nil {
+		retries = *pkg.Retries
+	}
+	var hooks map[string][]string
+	for stage, lines := range map[string][]string{"pre_install": pkg.PreInstall, "post_install": pkg.PostInstall, "pre_remove": pkg.PreRemove, "post_remove": pkg.PostRemove} {
+		if len(lines) == 0 {
+			continue
+		}
+		if hooks == nil {
+			hooks = make(map[string][]string)
+		}
+		hooks[stage] = lines
+	}
 	return Package{
 		Name:         []string(pkg.Name),
 		Manager:      pkg.Manager,
 		Version:      pkg.Version,
 		Optional:     pkg.Optional,
 		Dependencies: pkg.Dependencies,
+		Timeout:      timeout,
+		Retries:      retries,
+		RetryBackoff: backoff,
+		Hooks:        hooks,
 	}
 }
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+	"voltig/internal/semver"
+)
+
 // Package represents a software package in the system.
 type Package struct {
 	Name         []string
@@ -7,11 +14,63 @@ type Package struct {
 	Version      string
 	Optional     bool
 	Dependencies []string
+	Reinstall    bool          // install even if already present
+	Timeout      time.Duration // zero means manager.PackageTimeout
+	Retries      int           // extra attempts after a retryable failure
+	RetryBackoff time.Duration // delay before the first retry, doubled after each
+	// Hooks holds the shell commands run around an operation, keyed by
+	// stage: pre_install, post_install, pre_remove or post_remove.
+	Hooks map[string][]string
 }
 
 // PackageStatus represents the status of a package (installed, missing, etc.).
 type PackageStatus struct {
 	Name    string
 	Status  string // e.g., installed, missing, outdated
-	Version string
+	Version Version
+}
+
+// Version is an installed version as a manager or tool reported it.
+type Version struct {
+	Raw    string `json:"raw"`              // as reported, e.g. "1.7.1_1" from brew
+	Semver string `json:"semver,omitempty"` // normalized, e.g. "1.7.1"; empty if Raw has no version
+	Source string `json:"source,omitempty"` // who reported it, e.g. brew, nvm or external
+}
+
+/*
+ParseVersion builds the Version source reported as raw, normalizing the first
+version found in it to major.minor.patch, so "v20.11" becomes "20.11.0".
+*/
+func ParseVersion(raw, source string) Version {
+	v := Version{Raw: strings.TrimSpace(raw), Source: source}
+	if parsed, err := semver.Parse(v.Raw); err == nil {
+		v.Semver = fmt.Sprintf("%d.%d.%d", parsed.Major, parsed.Minor, parsed.Patch)
+		if parsed.Pre != "" {
+			v.Semver += "-" + parsed.Pre
+		}
+	}
+	return v
+}
+
+// String returns the normalized version, or the raw one if it has none.
+func (v Version) String() string {
+	if v.Semver != "" {
+		return v.Semver
+	}
+	return v.Raw
+}
+
+// Resolved is the exact installed version of a package and where it came from.
+type Resolved struct {
+	Version  string
+	Source   string // e.g. the Homebrew tap
+	Checksum string // e.g. sha256:<hex>, empty when the manager doesn't report one
+}
+
+// OutdatedPackage is an installed package with a newer version available.
+type OutdatedPackage struct {
+	Name    string `json:"name"`
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	Pinned  bool   `json:"pinned,omitempty"`
 }
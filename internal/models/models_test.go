@@ -6,9 +6,9 @@ import (
 )
 
 func TestPackageStatusEquality(t *testing.T) {
-	ps1 := PackageStatus{Name: "foo", Version: "1.0.0", Status: "installed"}
-	ps2 := PackageStatus{Name: "foo", Version: "1.0.0", Status: "installed"}
-	ps3 := PackageStatus{Name: "bar", Version: "2.0.0", Status: "missing"}
+	ps1 := PackageStatus{Name: "foo", Version: ParseVersion("1.0.0", "brew"), Status: "installed"}
+	ps2 := PackageStatus{Name: "foo", Version: ParseVersion("1.0.0", "brew"), Status: "installed"}
+	ps3 := PackageStatus{Name: "bar", Version: ParseVersion("2.0.0", "brew"), Status: "missing"}
 
 	if ps1 != ps2 {
 		t.Errorf("Expected ps1 and ps2 to be equal")
@@ -18,6 +18,27 @@ func TestPackageStatusEquality(t *testing.T) {
 	}
 }
 
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Version
+	}{
+		{"1.7.1_1\n", Version{Raw: "1.7.1_1", Semver: "1.7.1", Source: "brew"}},
+		{"v20.11", Version{Raw: "v20.11", Semver: "20.11.0", Source: "brew"}},
+		{"3.0.0-rc.1", Version{Raw: "3.0.0-rc.1", Semver: "3.0.0-rc.1", Source: "brew"}},
+		{"unknown", Version{Raw: "unknown", Source: "brew"}},
+	}
+	for _, tt := range tests {
+		got := ParseVersion(tt.raw, "brew")
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+	if s := ParseVersion("unknown", "").String(); s != "unknown" {
+		t.Errorf("String() = %q, want the raw version", s)
+	}
+}
+
 func TestToModel(t *testing.T) {
 	ps := ToModel(config.Package{
 		Name:         []string{"testpkg"},
@@ -29,4 +50,12 @@ func TestToModel(t *testing.T) {
 	if ps.Name[0] != "testpkg" || ps.Version != "1.2.3" {
 		t.Errorf("ToModel did not copy fields correctly")
 	}
+	if ps.Hooks != nil {
+		t.Errorf("expected no hooks, got %v", ps.Hooks)
+	}
+
+	ps = ToModel(config.Package{Name: []string{"pre-commit"}, Manager: "brew", PostInstall: config.StringList{"pre-commit install"}})
+	if len(ps.Hooks) != 1 || ps.Hooks["post_install"][0] != "pre-commit install" {
+		t.Errorf("ToModel did not copy hooks: %v", ps.Hooks)
+	}
 }
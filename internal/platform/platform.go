@@ -0,0 +1,160 @@
+/*
+Package platform evaluates `platforms:` and `when:` conditions from voltig.yml
+against the current host.
+*/
+package platform
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"voltig/config"
+)
+
+// Host describes the machine conditions are evaluated against.
+type Host struct {
+	OS      string
+	Arch    string
+	Distros []string // os-release ID followed by ID_LIKE entries
+	// LookupEnv and LookPath allow stubbing the environment in tests.
+	LookupEnv func(string) (string, bool)
+	LookPath  func(string) (string, error)
+}
+
+var (
+	currentOnce sync.Once
+	current     Host
+)
+
+// Current returns the host voltig is running on.
+func Current() Host {
+	currentOnce.Do(func() {
+		current = Host{
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			LookupEnv: os.LookupEnv,
+			LookPath:  exec.LookPath,
+		}
+		if runtime.GOOS == "linux" {
+			current.Distros = readDistros("/etc/os-release")
+		}
+	})
+	return current
+}
+
+// readDistros extracts ID and ID_LIKE from an os-release file.
+func readDistros(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+	var id string
+	var like []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			id = value
+		case "ID_LIKE":
+			like = strings.Fields(value)
+		}
+	}
+	if id == "" {
+		return like
+	}
+	return append([]string{id}, like...)
+}
+
+var osAliases = map[string]string{"macos": "darwin", "osx": "darwin", "mac": "darwin"}
+var archAliases = map[string]string{"x86_64": "amd64", "x64": "amd64", "aarch64": "arm64"}
+
+func normalize(value string, aliases map[string]string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := aliases[value]; ok {
+		return alias
+	}
+	return value
+}
+
+// Matches reports whether the host satisfies platforms and when. When it
+// does not, the returned reason describes the first failed check.
+func (h Host) Matches(platforms []string, when *config.Condition) (bool, string) {
+	if len(platforms) > 0 && !h.matchesPlatforms(platforms) {
+		return false, "platform " + h.OS + "/" + h.Arch + " not in " + strings.Join(platforms, ", ")
+	}
+	if when == nil {
+		return true, ""
+	}
+	if len(when.OS) > 0 && !anyOf(when.OS, func(v string) bool { return normalize(v, osAliases) == h.OS }) {
+		return false, "os " + h.OS + " not in " + strings.Join(when.OS, ", ")
+	}
+	if len(when.Arch) > 0 && !anyOf(when.Arch, func(v string) bool { return normalize(v, archAliases) == h.Arch }) {
+		return false, "arch " + h.Arch + " not in " + strings.Join(when.Arch, ", ")
+	}
+	if len(when.Distro) > 0 && !anyOf(when.Distro, h.hasDistro) {
+		return false, "distro not in " + strings.Join(when.Distro, ", ")
+	}
+	if len(when.Env) > 0 && !anyOf(when.Env, func(v string) bool {
+		_, ok := h.LookupEnv(v)
+		return ok
+	}) {
+		return false, "env " + strings.Join(when.Env, ", ") + " not set"
+	}
+	if len(when.Command) > 0 && !anyOf(when.Command, func(v string) bool {
+		_, err := h.LookPath(v)
+		return err == nil
+	}) {
+		return false, "command " + strings.Join(when.Command, ", ") + " not found"
+	}
+	return true, ""
+}
+
+// matchesPlatforms checks `os` or `os/arch` entries against the host.
+func (h Host) matchesPlatforms(platforms []string) bool {
+	return anyOf(platforms, func(p string) bool {
+		osName, arch, hasArch := strings.Cut(p, "/")
+		if normalize(osName, osAliases) != h.OS {
+			return false
+		}
+		return !hasArch || normalize(arch, archAliases) == h.Arch
+	})
+}
+
+func (h Host) hasDistro(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, d := range h.Distros {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+func anyOf(values []string, fn func(string) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// PackageMatches reports whether pkg applies to the current host.
+func PackageMatches(pkg config.Package) (bool, string) {
+	return Current().Matches(pkg.Platforms, pkg.When)
+}
+
+// CommandMatches reports whether the custom command applies to the current host.
+func CommandMatches(c config.CustomCommand) (bool, string) {
+	return Current().Matches(c.Platforms, c.When)
+}
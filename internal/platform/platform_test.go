@@ -0,0 +1,80 @@
+package platform
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"voltig/config"
+)
+
+func testHost() Host {
+	return Host{
+		OS:      "linux",
+		Arch:    "arm64",
+		Distros: []string{"ubuntu", "debian"},
+		LookupEnv: func(key string) (string, bool) {
+			if key == "CI" {
+				return "true", true
+			}
+			return "", false
+		},
+		LookPath: func(bin string) (string, error) {
+			if bin == "docker" {
+				return "/usr/bin/docker", nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	h := testHost()
+	tests := []struct {
+		name      string
+		platforms []string
+		when      *config.Condition
+		want      bool
+	}{
+		{"no conditions", nil, nil, true},
+		{"os only", []string{"linux"}, nil, true},
+		{"macos alias", []string{"macos"}, nil, false},
+		{"os and arch", []string{"darwin", "linux/aarch64"}, nil, true},
+		{"wrong arch", []string{"linux/amd64"}, nil, false},
+		{"when os", nil, &config.Condition{OS: []string{"darwin"}}, false},
+		{"when distro like", nil, &config.Condition{Distro: []string{"debian"}}, true},
+		{"when distro", nil, &config.Condition{Distro: []string{"arch"}}, false},
+		{"when env", nil, &config.Condition{Env: []string{"CI"}}, true},
+		{"when env missing", nil, &config.Condition{Env: []string{"NOPE"}}, false},
+		{"when command", nil, &config.Condition{Command: []string{"podman", "docker"}}, true},
+		{"when command missing", nil, &config.Condition{Command: []string{"podman"}}, false},
+		{"all fields anded", []string{"linux"}, &config.Condition{Arch: []string{"arm64"}, Env: []string{"NOPE"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := h.Matches(tt.platforms, tt.when)
+			if got != tt.want {
+				t.Errorf("got %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Errorf("expected a reason for non-matching conditions")
+			}
+		})
+	}
+}
+
+func TestReadDistros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := "NAME=\"Pop!_OS\"\nID=pop\nID_LIKE=\"ubuntu debian\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write os-release: %v", err)
+	}
+	got := readDistros(path)
+	if len(got) != 3 || got[0] != "pop" || got[2] != "debian" {
+		t.Errorf("unexpected distros: %v", got)
+	}
+	if readDistros(filepath.Join(t.TempDir(), "missing")) != nil {
+		t.Errorf("expected nil for missing file")
+	}
+}
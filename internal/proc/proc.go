@@ -0,0 +1,42 @@
+/*
+Package proc starts subprocesses bound to a context. When the context is
+cancelled (Ctrl-C, SIGTERM or a --timeout) the process is asked to stop with
+SIGTERM and killed after GracePeriod.
+*/
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// GracePeriod is how long a cancelled process gets to exit after SIGTERM
+// before it is killed.
+var GracePeriod = 5 * time.Second
+
+/*
+Command returns an exec.Cmd for name running in its own process group, so
+cancelling ctx stops the process and every child it spawned. Use it for
+non-interactive commands; the process doesn't receive terminal signals itself.
+*/
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setGroup(cmd)
+	cmd.Cancel = func() error { return terminate(cmd, true) }
+	cmd.WaitDelay = GracePeriod + time.Second
+	return cmd
+}
+
+/*
+Attached is like Command but leaves the process in voltig's process group, so
+it keeps the terminal for interactive use (prompts, editors) and receives
+Ctrl-C from the terminal directly. On cancellation only the process itself is
+signalled.
+*/
+func Attached(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error { return terminate(cmd, false) }
+	cmd.WaitDelay = GracePeriod + time.Second
+	return cmd
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package proc
+
+import "os/exec"
+
+func setGroup(*exec.Cmd) {}
+
+// terminate kills the process; there are no process groups or SIGTERM here.
+func terminate(cmd *exec.Cmd, _ bool) error {
+	return cmd.Process.Kill()
+}
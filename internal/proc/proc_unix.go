@@ -0,0 +1,38 @@
+//go:build unix
+
+package proc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+func setGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+/*
+terminate sends SIGTERM to the process (or its whole group) and SIGKILL once
+GracePeriod has passed without it exiting. Once Wait has reaped the process
+its pid, and with it the group id, may belong to another process, so the
+SIGKILL is skipped then.
+*/
+func terminate(cmd *exec.Cmd, group bool) error {
+	pid := cmd.Process.Pid
+	if group {
+		pid = -pid
+	}
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	time.AfterFunc(GracePeriod, func() {
+		if err := cmd.Process.Signal(syscall.Signal(0)); errors.Is(err, os.ErrProcessDone) {
+			return
+		}
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	})
+	return nil
+}
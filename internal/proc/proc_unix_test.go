@@ -0,0 +1,65 @@
+//go:build unix
+
+package proc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommand_CancelStopsGroup(t *testing.T) {
+	orig := GracePeriod
+	GracePeriod = 2 * time.Second
+	t.Cleanup(func() { GracePeriod = orig })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// sleep inherits the stdout pipe, so Wait only returns early if the
+	// grandchild was stopped along with the shell
+	cmd := Command(ctx, "sh", "-c", "sleep 30; echo done")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Error("expected an error from a cancelled command")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled command took %s to stop; grandchild left running?", elapsed)
+	}
+	if out.Len() != 0 {
+		t.Errorf("command ran to completion: %q", out.String())
+	}
+}
+
+func TestCommand_KillsAfterGracePeriod(t *testing.T) {
+	orig := GracePeriod
+	GracePeriod = 200 * time.Millisecond
+	t.Cleanup(func() { GracePeriod = orig })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The ignored SIGTERM is inherited by sleep, so only SIGKILL stops them
+	cmd := Command(ctx, "sh", "-c", "trap '' TERM; sleep 30; echo done")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Error("expected an error from a killed command")
+	}
+	if elapsed := time.Since(start); elapsed < GracePeriod || elapsed > GracePeriod+time.Second {
+		t.Errorf("command stopped after %s; want SIGKILL after %s", elapsed, GracePeriod)
+	}
+	if out.Len() != 0 {
+		t.Errorf("command ran to completion: %q", out.String())
+	}
+}
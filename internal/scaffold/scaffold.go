@@ -0,0 +1,246 @@
+/*
+Package scaffold detects the type of a project and generates a starter
+voltig.yml for `voltig init`.
+*/
+package scaffold
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project is a project type detected from a marker file.
+type Project struct {
+	Kind   string // go, node, rust, python, make
+	Marker string // file that identified the project, e.g. go.mod
+}
+
+// PackageProposal is a package voltig init suggests adding.
+type PackageProposal struct {
+	Name    string
+	Manager string
+	Version string
+	Comment string
+}
+
+// CommandProposal is a custom command voltig init suggests adding.
+type CommandProposal struct {
+	Name    string
+	Summary string
+	Command string
+}
+
+// Plan is the content of the generated voltig.yml.
+type Plan struct {
+	Projects []Project
+	Packages []PackageProposal
+	Commands []CommandProposal
+}
+
+// markers maps marker files to project kinds in detection order.
+var markers = []Project{
+	{Kind: "go", Marker: "go.mod"},
+	{Kind: "node", Marker: "package.json"},
+	{Kind: "rust", Marker: "Cargo.toml"},
+	{Kind: "python", Marker: "pyproject.toml"},
+	{Kind: "make", Marker: "Makefile"},
+}
+
+// Detect returns the project types found in dir.
+func Detect(dir string) []Project {
+	var found []Project
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(dir, m.Marker)); err == nil {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// nodePackageManager picks npm, pnpm or yarn from the lockfile in dir.
+func nodePackageManager(dir string) string {
+	switch {
+	case exists(dir, "pnpm-lock.yaml"):
+		return "pnpm"
+	case exists(dir, "yarn.lock"):
+		return "yarn"
+	default:
+		return "npm"
+	}
+}
+
+// pythonTool picks uv or poetry from the lockfile in dir, if any.
+func pythonTool(dir string) string {
+	switch {
+	case exists(dir, "uv.lock"):
+		return "uv"
+	case exists(dir, "poetry.lock"):
+		return "poetry"
+	default:
+		return ""
+	}
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_.-]*)\s*:([^=]|$)`)
+
+// makeTargets returns the targets defined in a Makefile.
+func makeTargets(path string) map[string]bool {
+	targets := make(map[string]bool)
+	f, err := os.Open(path)
+	if err != nil {
+		return targets
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if m := makeTargetPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			targets[m[1]] = true
+		}
+	}
+	return targets
+}
+
+/*
+Propose builds the packages and build/test/check commands for the projects
+detected in dir. Makefile targets take precedence for commands since they
+usually wrap the language tooling; otherwise the first detected language wins.
+The lint command is named check, as lint is a core command.
+*/
+func Propose(dir string, projects []Project) Plan {
+	plan := Plan{Projects: projects}
+	commands := make(map[string]CommandProposal)
+	addCommand := func(name, summary, command string) {
+		if _, taken := commands[name]; !taken {
+			commands[name] = CommandProposal{Name: name, Summary: summary, Command: command}
+		}
+	}
+	addPackage := func(name, comment string) {
+		for _, p := range plan.Packages {
+			if p.Name == name {
+				return
+			}
+		}
+		plan.Packages = append(plan.Packages, PackageProposal{Name: name, Manager: "brew", Version: "latest", Comment: comment})
+	}
+
+	for _, p := range projects {
+		if p.Kind != "make" {
+			continue
+		}
+		addPackage("make", "Build tool (detected Makefile)")
+		targets := makeTargets(filepath.Join(dir, p.Marker))
+		if targets["build"] || targets["all"] {
+			addCommand("build", "Build the project", "make")
+		}
+		if targets["test"] {
+			addCommand("test", "Test the project", "make test")
+		}
+		if targets["lint"] {
+			addCommand("check", "Lint the project", "make lint")
+		}
+	}
+	for _, p := range projects {
+		switch p.Kind {
+		case "go":
+			addPackage("go", "Go toolchain (detected go.mod)")
+			addPackage("golangci-lint", "Go linter")
+			addCommand("build", "Build the project", "go build ./...")
+			addCommand("test", "Test the project", "go test ./...")
+			addCommand("check", "Lint the project", "golangci-lint run")
+		case "node":
+			pm := nodePackageManager(dir)
+			addPackage("node", "Node.js runtime (detected package.json)")
+			if pm != "npm" {
+				addPackage(pm, "Node package manager (detected lockfile)")
+			}
+			addCommand("build", "Build the project", pm+" run build")
+			addCommand("test", "Test the project", pm+" test")
+			addCommand("check", "Lint the project", pm+" run lint")
+		case "rust":
+			addPackage("rust", "Rust toolchain (detected Cargo.toml)")
+			addCommand("build", "Build the project", "cargo build")
+			addCommand("test", "Test the project", "cargo test")
+			addCommand("check", "Lint the project", "cargo clippy -- -D warnings")
+		case "python":
+			addPackage("python3", "Python interpreter (detected pyproject.toml)")
+			run := ""
+			if tool := pythonTool(dir); tool != "" {
+				addPackage(tool, "Python project manager (detected lockfile)")
+				run = tool + " run "
+			}
+			addPackage("ruff", "Python linter")
+			addCommand("build", "Build the project", "python3 -m build")
+			addCommand("test", "Test the project", run+"pytest")
+			addCommand("check", "Lint the project", "ruff check .")
+		}
+	}
+	for _, name := range []string{"build", "test", "check"} {
+		if c, ok := commands[name]; ok {
+			plan.Commands = append(plan.Commands, c)
+		}
+	}
+	return plan
+}
+
+// Render writes the plan as a commented voltig.yml.
+func Render(plan Plan) string {
+	var b strings.Builder
+	b.WriteString("# voltig.yml generated by `voltig init`\n")
+	if len(plan.Projects) > 0 {
+		var kinds []string
+		for _, p := range plan.Projects {
+			kinds = append(kinds, p.Kind)
+		}
+		b.WriteString("# Detected project types: " + strings.Join(kinds, ", ") + "\n")
+	}
+	b.WriteString("# Run `voltig lint` after editing and `voltig schema` for editor support.\n\n")
+
+	b.WriteString("# Packages installed with `voltig install`\n")
+	if len(plan.Packages) == 0 {
+		b.WriteString("packages: []\n")
+	} else {
+		b.WriteString("packages:\n")
+		for _, p := range plan.Packages {
+			if p.Comment != "" {
+				b.WriteString("  # " + p.Comment + "\n")
+			}
+			b.WriteString("  - name: " + scalar(p.Name) + "\n")
+			b.WriteString("    manager: " + scalar(p.Manager) + "\n")
+			if p.Version != "" {
+				b.WriteString("    version: " + scalar(p.Version) + "\n")
+			}
+		}
+	}
+
+	b.WriteString("\n# Project commands, run with `voltig <name>`\n")
+	if len(plan.Commands) == 0 {
+		b.WriteString("commands: {}\n")
+	} else {
+		b.WriteString("commands:\n")
+		for _, c := range plan.Commands {
+			b.WriteString("  " + scalar(c.Name) + ":\n")
+			b.WriteString("    summary: " + scalar(c.Summary) + "\n")
+			b.WriteString("    command: " + scalar(c.Command) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// scalar encodes s as a YAML scalar, quoting it only when needed.
+func scalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return `"` + s + `"`
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,79 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voltig/config"
+)
+
+func touch(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestDetectAndPropose(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "go.mod", "module x\n")
+	touch(t, dir, "package.json", "{}")
+	touch(t, dir, "pnpm-lock.yaml", "")
+	touch(t, dir, "Makefile", "VAR := 1\nbuild:\n\tgo build\ntest: build\n\tgo test ./...\n")
+
+	projects := Detect(dir)
+	var kinds []string
+	for _, p := range projects {
+		kinds = append(kinds, p.Kind)
+	}
+	if strings.Join(kinds, ",") != "go,node,make" {
+		t.Fatalf("unexpected projects: %v", kinds)
+	}
+
+	plan := Propose(dir, projects)
+	var pkgs []string
+	for _, p := range plan.Packages {
+		pkgs = append(pkgs, p.Name)
+	}
+	if strings.Join(pkgs, ",") != "make,go,golangci-lint,node,pnpm" {
+		t.Errorf("unexpected packages: %v", pkgs)
+	}
+	want := map[string]string{"build": "make", "test": "make test", "check": "golangci-lint run"}
+	if len(plan.Commands) != len(want) {
+		t.Fatalf("unexpected commands: %+v", plan.Commands)
+	}
+	for _, c := range plan.Commands {
+		if want[c.Name] != c.Command {
+			t.Errorf("%s: got %q, want %q", c.Name, c.Command, want[c.Name])
+		}
+	}
+}
+
+func TestRenderLoadsAsConfig(t *testing.T) {
+	plan := Plan{
+		Projects: []Project{{Kind: "python", Marker: "pyproject.toml"}},
+		Packages: []PackageProposal{{Name: "python3", Manager: "brew", Version: "latest", Comment: "Python"}},
+		Commands: []CommandProposal{{Name: "lint", Summary: "Lint: strict", Command: "ruff check . # all"}},
+	}
+	out := Render(plan)
+	if !strings.Contains(out, "# Python\n") || !strings.Contains(out, "# Detected project types: python") {
+		t.Errorf("expected comments in rendered config:\n%s", out)
+	}
+	path := filepath.Join(t.TempDir(), "voltig.yml")
+	touch(t, filepath.Dir(path), "voltig.yml", out)
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("rendered config does not load: %v\n%s", err, out)
+	}
+	if cfg.Commands["lint"].Command != "ruff check . # all" || cfg.Packages[0].Name[0] != "python3" {
+		t.Errorf("rendered config round-trip mismatch: %+v", cfg)
+	}
+
+	empty := Render(Plan{})
+	touch(t, filepath.Dir(path), "voltig.yml", empty)
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Errorf("empty rendered config does not load: %v\n%s", err, empty)
+	}
+}
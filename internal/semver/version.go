@@ -0,0 +1,336 @@
+/*
+Package semver parses package versions and the version constraints used in
+voltig.yml, such as `^1.4`, `~20.10`, `>=3.11,<3.13` or `1.x || 2.x`.
+*/
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Missing minor or patch parts are zero.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // pre-release, e.g. "rc.1"
+	Original            string
+}
+
+var versionPattern = regexp.MustCompile(`v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?`)
+
+/*
+Parse extracts the first version found in s, so tool output such as
+"jq-1.7.1" or "node 20.11.1" parses as well as a bare "v20.11.1". Dotted
+versions are preferred over bare numbers, so "python3 3.12.1" is 3.12.1.
+*/
+func Parse(s string) (Version, error) {
+	matches := versionPattern.FindAllStringSubmatch(s, -1)
+	if matches == nil {
+		return Version{}, fmt.Errorf("no version found in %q", strings.TrimSpace(s))
+	}
+	m := matches[0]
+	for _, cand := range matches {
+		if cand[2] != "" {
+			m = cand
+			break
+		}
+	}
+	v := Version{Pre: m[4], Original: strings.TrimPrefix(m[0], "v")}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than o.
+// A pre-release sorts before the release it precedes.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	case v.Pre < o.Pre:
+		return -1
+	}
+	return 1
+}
+
+func (v Version) String() string {
+	if v.Original != "" {
+		return v.Original
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// comparator is a single bound such as `>=1.4.0`.
+type comparator struct {
+	op string // =, !=, >, >=, <, <=
+	v  Version
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func (c comparator) lower() bool {
+	return c.op == ">" || c.op == ">="
+}
+
+/*
+Constraint is a version requirement from voltig.yml. Comparators separated by
+commas or spaces must all hold; alternatives separated by `||` may each hold.
+An empty constraint or "latest" accepts any version.
+*/
+type Constraint struct {
+	raw  string
+	alts [][]comparator
+}
+
+// Any reports whether the constraint accepts every version.
+func (c Constraint) Any() bool {
+	return len(c.alts) == 0
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+var (
+	operatorPattern = regexp.MustCompile(`^(\^|~|>=|<=|!=|>|<|=)?\s*v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?$`)
+	// comparatorSplit splits "  >= 1.2, <2 " into ">= 1.2" and "<2".
+	comparatorSplit = regexp.MustCompile(`(\^|~|>=|<=|!=|>|<|=)?\s*[^\s,<>=!^~]+`)
+)
+
+// ParseConstraint parses a constraint such as `^1.4` or `>=3.11,<3.13`.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" || c.raw == "latest" || c.raw == "*" {
+		return c, nil
+	}
+	for _, alt := range strings.Split(c.raw, "||") {
+		parts := comparatorSplit.FindAllString(alt, -1)
+		if len(parts) == 0 || strings.Trim(comparatorSplit.ReplaceAllString(alt, ""), " ,") != "" {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q", s)
+		}
+		for _, piece := range strings.Split(alt, ",") {
+			if strings.TrimSpace(piece) == "" {
+				return Constraint{}, fmt.Errorf("invalid version constraint %q: empty term", s)
+			}
+		}
+		var comps []comparator
+		for _, part := range parts {
+			expanded, err := expand(part)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			comps = append(comps, expanded...)
+		}
+		c.alts = append(c.alts, comps)
+	}
+	return c, nil
+}
+
+// expand turns one operator term into plain comparators, e.g. `^1.4` into
+// `>=1.4.0 <2.0.0` and `1.2` into `>=1.2.0 <1.3.0`.
+func expand(term string) ([]comparator, error) {
+	m := operatorPattern.FindStringSubmatch(strings.TrimSpace(term))
+	if m == nil {
+		return nil, fmt.Errorf("bad term %q", term)
+	}
+	op := m[1]
+	var nums []int
+	for _, p := range m[2:5] {
+		if p == "" || p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, _ := strconv.Atoi(p)
+		nums = append(nums, n)
+	}
+	if len(nums) == 0 {
+		if op != "" && op != "=" {
+			return nil, fmt.Errorf("wildcard %q needs no operator", term)
+		}
+		return nil, nil
+	}
+	base := Version{Pre: m[5]}
+	for i, n := range nums {
+		switch i {
+		case 0:
+			base.Major = n
+		case 1:
+			base.Minor = n
+		case 2:
+			base.Patch = n
+		}
+	}
+	// next returns the smallest version above every version sharing the first
+	// n parts of base, e.g. 1.4.x -> 1.5.0.
+	next := func(n int) Version {
+		switch n {
+		case 1:
+			return Version{Major: base.Major + 1}
+		case 2:
+			return Version{Major: base.Major, Minor: base.Minor + 1}
+		}
+		return Version{Major: base.Major, Minor: base.Minor, Patch: base.Patch + 1}
+	}
+	switch op {
+	case "^":
+		// Allow changes that don't modify the left-most non-zero part
+		n := 1
+		if base.Major == 0 && len(nums) > 1 {
+			n = 2
+			if base.Minor == 0 && len(nums) > 2 {
+				n = 3
+			}
+		}
+		return []comparator{{">=", base}, {"<", next(n)}}, nil
+	case "~":
+		n := len(nums)
+		if n > 2 {
+			n = 2
+		}
+		return []comparator{{">=", base}, {"<", next(n)}}, nil
+	case "", "=":
+		if len(nums) == 3 {
+			return []comparator{{"=", base}}, nil
+		}
+		return []comparator{{">=", base}, {"<", next(len(nums))}}, nil
+	case ">":
+		if len(nums) < 3 {
+			return []comparator{{">=", next(len(nums))}}, nil
+		}
+	case "<=":
+		if len(nums) < 3 {
+			return []comparator{{"<", next(len(nums))}}, nil
+		}
+	}
+	return []comparator{{op, base}}, nil
+}
+
+var exactPattern = regexp.MustCompile(`^v?\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?$`)
+
+// Exact reports whether the constraint is a bare version such as `1.7` or
+// `20.11.1`, which backends can usually install directly.
+func (c Constraint) Exact() bool {
+	return exactPattern.MatchString(c.raw)
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	if c.Any() {
+		return true
+	}
+	for _, alt := range c.alts {
+		ok := true
+		for _, comp := range alt {
+			if !comp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Below reports whether v fails the constraint only because it is too old,
+// i.e. upgrading could satisfy it.
+func (c Constraint) Below(v Version) bool {
+	if c.Check(v) {
+		return false
+	}
+	for _, alt := range c.alts {
+		below := false
+		for _, comp := range alt {
+			if comp.check(v) {
+				continue
+			}
+			if !comp.lower() && !(comp.op == "=" && v.Compare(comp.v) < 0) {
+				below = false
+				break
+			}
+			below = true
+		}
+		if below {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the highest of candidates satisfying the constraint.
+func (c Constraint) Best(candidates []string) (Version, bool) {
+	var best Version
+	found := false
+	for _, s := range candidates {
+		v, err := Parse(s)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if !found || v.Compare(best) > 0 {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// Result of checking an installed version against a constraint.
+const (
+	StatusOK          = "ok"
+	StatusOutdated    = "outdated"
+	StatusUnsatisfied = "unsatisfied"
+)
+
+// Status classifies installed against constraint as ok, outdated (too old;
+// an upgrade would satisfy it) or unsatisfied. An unparseable installed
+// version is unsatisfied unless the constraint accepts anything.
+func Status(constraint Constraint, installed string) string {
+	if constraint.Any() {
+		return StatusOK
+	}
+	v, err := Parse(installed)
+	switch {
+	case err != nil:
+		return StatusUnsatisfied
+	case constraint.Check(v):
+		return StatusOK
+	case constraint.Below(v):
+		return StatusOutdated
+	}
+	return StatusUnsatisfied
+}
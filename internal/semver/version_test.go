@@ -0,0 +1,134 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"v20.11.1", "20.11.1"},
+		{"jq-1.7.1\n", "1.7.1"},
+		{"python3 3.12.1", "3.12.1"},
+		{"go version go1.22.0 darwin/arm64", "1.22.0"},
+		{"1.0.0-rc.1", "1.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if v.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, v, tt.want)
+		}
+	}
+	if _, err := Parse("unknown"); err == nil {
+		t.Error("expected error for input without a version")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"2.0.0", "10.0.0", -1},
+	}
+	for _, tt := range tests {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"latest", "0.0.1", true},
+		{"", "9.9.9", true},
+		{"^1.4", "1.4.0", true},
+		{"^1.4", "1.9.2", true},
+		{"^1.4", "2.0.0", false},
+		{"^1.4", "1.3.9", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"~20.10", "20.10.5", true},
+		{"~20.10", "20.11.0", false},
+		{"~1", "1.9.0", true},
+		{">=3.11,<3.13", "3.12.4", true},
+		{">=3.11, <3.13", "3.13.0", false},
+		{">=3.11 <3.13", "3.10.2", false},
+		{"20", "20.11.1", true},
+		{"20", "21.0.0", false},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"1.x || 3.x", "3.1.0", true},
+		{"1.x || 3.x", "2.1.0", false},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"!=1.2.3", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) failed: %v", tt.constraint, err)
+			continue
+		}
+		v, _ := Parse(tt.version)
+		if got := c.Check(v); got != tt.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"^", ">=abc", "1.2 - 1.4", "~>1.2", ">=1.0,,"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		constraint, installed, want string
+	}{
+		{"^1.4", "1.5.0", StatusOK},
+		{"^1.4", "1.3.0", StatusOutdated},
+		{"^1.4", "2.1.0", StatusUnsatisfied},
+		{">=3.11,<3.13", "3.9.0", StatusOutdated},
+		{"1.2.3", "1.2.0", StatusOutdated},
+		{"1.2.3", "1.2.4", StatusUnsatisfied},
+		{"latest", "unknown", StatusOK},
+		{"^1", "unknown", StatusUnsatisfied},
+	}
+	for _, tt := range tests {
+		c, _ := ParseConstraint(tt.constraint)
+		if got := Status(c, tt.installed); got != tt.want {
+			t.Errorf("Status(%q, %q) = %s, want %s", tt.constraint, tt.installed, got, tt.want)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	c, _ := ParseConstraint("^18")
+	best, ok := c.Best([]string{"16", "18", "18.19.1", "20", "22.1.0"})
+	if !ok || best.String() != "18.19.1" {
+		t.Errorf("Best = %v, %v; want 18.19.1", best, ok)
+	}
+	if _, ok := c.Best([]string{"20"}); ok {
+		t.Error("expected no satisfying candidate")
+	}
+}
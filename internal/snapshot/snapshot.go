@@ -0,0 +1,167 @@
+/*
+Package snapshot captures the packages installed on a machine, both everything
+the system package manager reports and the status of each voltig.yml package,
+so the toolchain can be compared or restored later, e.g. after an OS upgrade.
+*/
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"voltig/internal/state"
+)
+
+// Package is one installed package in a snapshot.
+type Package struct {
+	Name    string `json:"name"`
+	Manager string `json:"manager"`
+	Version string `json:"version,omitempty"`
+	// Status, Constraint and Config are only set for voltig.yml packages.
+	Status     string `json:"status,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Config     bool   `json:"config,omitempty"`
+}
+
+func (p Package) key() string {
+	return p.Manager + ":" + p.Name
+}
+
+// Snapshot is the content of a snapshot file.
+type Snapshot struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Host      string    `json:"host,omitempty"`
+	Platform  string    `json:"platform"`         // os/arch
+	Config    string    `json:"config,omitempty"` // voltig.yml the config packages came from
+	Packages  []Package `json:"packages"`
+}
+
+// Add records pkg, replacing an entry for the same manager and name. Config
+// packages replace scanned ones, so their status and constraint are kept.
+func (s *Snapshot) Add(pkg Package) {
+	for i, existing := range s.Packages {
+		if existing.key() == pkg.key() {
+			if pkg.Config || !existing.Config {
+				s.Packages[i] = pkg
+			}
+			return
+		}
+	}
+	s.Packages = append(s.Packages, pkg)
+}
+
+// Dir returns the directory snapshots are saved in, next to the state file.
+func Dir() string {
+	p := state.DefaultPath()
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(p), "snapshots")
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// PathFor returns the file of the snapshot called name. A name containing a
+// path separator or ending in .json is used as a path as-is, so snapshots
+// can be kept anywhere.
+func PathFor(name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.HasSuffix(name, ".json") {
+		return name, nil
+	}
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return filepath.Join(Dir(), name+".json"), nil
+}
+
+// Load reads the snapshot called name (see PathFor).
+func Load(name string) (*Snapshot, error) {
+	path, err := PathFor(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no snapshot %q (%s)", name, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes s to path, sorted by manager and name. It refuses to overwrite
+// an existing snapshot unless force is set.
+func (s *Snapshot) Save(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("snapshot %s already exists", path)
+	}
+	sort.Slice(s.Packages, func(i, j int) bool { return s.Packages[i].key() < s.Packages[j].key() })
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Change kinds reported by Diff.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a package that differs between two snapshots.
+type Change struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Manager string `json:"manager"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// Diff returns the packages added, removed or at a different version in b
+// compared to a, sorted by manager and name.
+func Diff(a, b *Snapshot) []Change {
+	before := make(map[string]Package)
+	for _, p := range a.Packages {
+		before[p.key()] = p
+	}
+	changes := []Change{}
+	seen := make(map[string]bool)
+	for _, p := range b.Packages {
+		seen[p.key()] = true
+		old, ok := before[p.key()]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Added, Name: p.Name, Manager: p.Manager, To: p.Version})
+		case old.Version != p.Version:
+			changes = append(changes, Change{Kind: Changed, Name: p.Name, Manager: p.Manager, From: old.Version, To: p.Version})
+		}
+	}
+	for _, p := range a.Packages {
+		if !seen[p.key()] {
+			changes = append(changes, Change{Kind: Removed, Name: p.Name, Manager: p.Manager, From: p.Version})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Manager != changes[j].Manager {
+			return changes[i].Manager < changes[j].Manager
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
@@ -0,0 +1,73 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	s := &Snapshot{Name: "before-upgrade", Platform: "darwin/arm64"}
+	s.Add(Package{Name: "node", Manager: "brew", Version: "20.11.1", Status: "installed", Constraint: "^20", Config: true})
+	s.Add(Package{Name: "jq", Manager: "brew", Version: "1.7.1"})
+	// A scanned entry doesn't replace the config entry for the same package
+	s.Add(Package{Name: "node", Manager: "brew", Version: "20.11.1"})
+
+	path, err := PathFor("before-upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(path, false); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := s.Save(path, false); err == nil {
+		t.Error("expected Save to refuse overwriting without force")
+	}
+	got, err := Load("before-upgrade")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(got.Packages) != 2 || got.Packages[0].Name != "jq" || !got.Packages[1].Config {
+		t.Errorf("unexpected packages: %+v", got.Packages)
+	}
+	if _, err := Load("missing"); err == nil {
+		t.Error("expected an error for a missing snapshot")
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if p, err := PathFor("./backup.json"); err != nil || p != "./backup.json" {
+		t.Errorf("PathFor(path) = %q, %v", p, err)
+	}
+	if p, _ := PathFor("pre-14.2"); filepath.Base(p) != "pre-14.2.json" {
+		t.Errorf("PathFor(name) = %q", p)
+	}
+	if _, err := PathFor("../x"); err != nil {
+		t.Errorf("relative paths are allowed: %v", err)
+	}
+	if _, err := PathFor("bad name"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := &Snapshot{Packages: []Package{
+		{Name: "jq", Manager: "brew", Version: "1.7"},
+		{Name: "node", Manager: "brew", Version: "20.10.0"},
+		{Name: "wget", Manager: "brew", Version: "1.21"},
+	}}
+	b := &Snapshot{Packages: []Package{
+		{Name: "jq", Manager: "brew", Version: "1.7"},
+		{Name: "node", Manager: "brew", Version: "20.11.1"},
+		{Name: "go", Manager: "brew", Version: "1.22.1"},
+	}}
+	want := []Change{
+		{Kind: Added, Name: "go", Manager: "brew", To: "1.22.1"},
+		{Kind: Changed, Name: "node", Manager: "brew", From: "20.10.0", To: "20.11.1"},
+		{Kind: Removed, Name: "wget", Manager: "brew", From: "1.21"},
+	}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v, want %+v", got, want)
+	}
+}
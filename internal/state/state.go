@@ -0,0 +1,143 @@
+/*
+Package state keeps machine-local voltig state, such as which packages voltig
+installed itself, so `voltig sync --prune` never removes packages it doesn't
+own.
+*/
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultPath returns the state file location, honouring XDG_STATE_HOME. It
+// is a variable so tests can point it elsewhere.
+var DefaultPath = func() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "voltig", "state.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "voltig", "state.json")
+}
+
+// Owned records a package voltig installed.
+type Owned struct {
+	Manager     string    `json:"manager"`
+	Names       []string  `json:"names"`
+	Version     string    `json:"version,omitempty"`
+	Config      string    `json:"config"` // absolute path of the voltig.yml that installed it
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// State is the content of the state file.
+type State struct {
+	Path     string           `json:"-"`
+	Packages map[string]Owned `json:"packages"`
+}
+
+func key(manager, name string) string {
+	return manager + ":" + name
+}
+
+// Load reads the state file at path. A missing file yields an empty state.
+func Load(path string) (*State, error) {
+	s := &State{Path: path, Packages: make(map[string]Owned)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Packages == nil {
+		s.Packages = make(map[string]Owned)
+	}
+	return s, nil
+}
+
+// Own marks a package as installed by voltig from the config at configPath.
+func (s *State) Own(manager string, names []string, version, configPath string) {
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	s.Packages[key(manager, names[0])] = Owned{
+		Manager:     manager,
+		Names:       names,
+		Version:     version,
+		Config:      configPath,
+		InstalledAt: time.Now().UTC(),
+	}
+}
+
+// Owns reports whether voltig installed the package.
+func (s *State) Owns(manager, name string) bool {
+	_, ok := s.Packages[key(manager, name)]
+	return ok
+}
+
+// Disown forgets a package, e.g. after it was removed.
+func (s *State) Disown(manager, name string) {
+	delete(s.Packages, key(manager, name))
+}
+
+// DisownName forgets one name of a package recorded under manager, keeping
+// the record for its other names.
+func (s *State) DisownName(manager, name string) {
+	for k, o := range s.Packages {
+		if o.Manager != manager {
+			continue
+		}
+		var kept []string
+		for _, n := range o.Names {
+			if n != name {
+				kept = append(kept, n)
+			}
+		}
+		if len(kept) == len(o.Names) {
+			continue
+		}
+		delete(s.Packages, k)
+		if len(kept) > 0 {
+			o.Names = kept
+			s.Packages[key(manager, kept[0])] = o
+		}
+		return
+	}
+}
+
+// OwnedBy returns the packages installed from the config at configPath,
+// sorted by name.
+func (s *State) OwnedBy(configPath string) []Owned {
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	var owned []Owned
+	for _, o := range s.Packages {
+		if o.Config == configPath {
+			owned = append(owned, o)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].Names[0] < owned[j].Names[0] })
+	return owned
+}
+
+// Save writes the state file, creating its directory if needed.
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, append(data, '\n'), 0o644)
+}
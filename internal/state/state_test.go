@@ -0,0 +1,43 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateOwnership(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "voltig", "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfgA := filepath.Join(dir, "a", "voltig.yml")
+	cfgB := filepath.Join(dir, "b", "voltig.yml")
+	s.Own("brew", []string{"jq"}, "latest", cfgA)
+	s.Own("brew", []string{"node", "nodejs"}, "20", cfgA)
+	s.Own("brew", []string{"go"}, "", cfgB)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !s.Owns("brew", "node") || s.Owns("brew", "nodejs") || s.Owns("apt", "jq") {
+		t.Errorf("unexpected ownership: %+v", s.Packages)
+	}
+	owned := s.OwnedBy(cfgA)
+	if len(owned) != 2 || owned[0].Names[0] != "jq" || owned[1].Names[1] != "nodejs" {
+		t.Errorf("unexpected packages owned by %s: %+v", cfgA, owned)
+	}
+	s.Disown("brew", "jq")
+	if s.Owns("brew", "jq") || len(s.OwnedBy(cfgB)) != 1 {
+		t.Errorf("unexpected state after disown: %+v", s.Packages)
+	}
+	s.DisownName("brew", "node")
+	if s.Owns("brew", "node") || !s.Owns("brew", "nodejs") || s.OwnedBy(cfgA)[0].Version != "20" {
+		t.Errorf("unexpected state after disowning one name: %+v", s.Packages)
+	}
+}